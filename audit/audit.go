@@ -0,0 +1,256 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+// Package audit records the tasks that change something in an append-only JSON Lines file. Each entry
+// contains the hash of the previous entry, so that a modified, inserted, or removed entry breaks the chain
+// and is detected by Verify.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// lockStale is the age after which the lock file of an audit log is assumed to be left over from a process
+// that died, as appending an entry takes milliseconds
+const lockStale = 30 * time.Second
+
+// lockTimeout is how long Append waits for another process to release the lock file
+const lockTimeout = 10 * time.Second
+
+// Entry is an audit log entry
+type Entry struct {
+	Sequence   int                 `json:"sequence"`            // Position in the log, starting at 1
+	Time       time.Time           `json:"time"`                // Time the task completed
+	StartTime  time.Time           `json:"start_time"`          // Time the task started
+	RunID      string              `json:"run_id"`              // Workflow run
+	Workflow   string              `json:"workflow,omitempty"`  // Workflow file, empty for stdin
+	Step       string              `json:"step"`                // Position of the task in the workflow
+	Name       string              `json:"name,omitempty"`      // Task name
+	Task       string              `json:"task"`                // Task type
+	DryRun     bool                `json:"dryrun,omitempty"`    // The task ran as a dry run
+	Plan       bool                `json:"plan,omitempty"`      // The task ran in plan mode
+	User       string              `json:"user"`                // Operating system user
+	AWS        *shared.AWSIdentity `json:"aws,omitempty"`       // AWS caller identity, for AWS tasks
+	JiraUser   string              `json:"jira_user,omitempty"` // Jira user, for Jira tasks
+	Parameters map[string]any      `json:"parameters"`          // Task instructions with variables resolved and secrets redacted
+	Success    bool                `json:"success"`             // Outcome reported by the task
+	Changed    bool                `json:"changed"`             // The task changed something
+	Msg        string              `json:"msg,omitempty"`       // Task message, with secrets redacted
+	PrevHash   string              `json:"prev_hash"`           // Hash of the previous entry, empty for the first
+	Hash       string              `json:"hash"`                // Hash of this entry
+}
+
+// Log is an audit log file. Entries may be appended by several workflows and processes.
+type Log struct {
+	filename string
+	mu       sync.Mutex
+}
+
+// Open opens an audit log, creating it if it does not exist, and checks that its last entry can be read
+//
+//goland:noinspection GoUnusedExportedFunction
+func Open(filename string) (*Log, error) {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open audit log: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if _, err = lastEntry(f); err != nil {
+		return nil, fmt.Errorf("audit log %s: %w", filename, err)
+	}
+	return &Log{filename: filename}, nil
+}
+
+// Filename returns the name of the audit log file
+func (l *Log) Filename() string {
+	return l.filename
+}
+
+// Append chains an entry to the last entry of the log and writes it. The sequence and hashes of the entry
+// are set by Append.
+func (l *Log) Append(e Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	unlock, err := lock(l.filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(l.filename, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to open audit log: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	last, err := lastEntry(f)
+	if err != nil {
+		return fmt.Errorf("audit log %s: %w", l.filename, err)
+	}
+	e.Sequence, e.PrevHash, e.Hash = 1, "", ""
+	if last != nil {
+		e.Sequence, e.PrevHash = last.Sequence+1, last.Hash
+	}
+
+	// The entry is hashed as it is read back by Verify, e.g. with numbers as float64
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	var stored Entry
+	if err = json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+	if stored.Hash, err = stored.hash(); err != nil {
+		return err
+	}
+	if data, err = json.Marshal(stored); err != nil {
+		return err
+	}
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("unable to write audit log: %w", err)
+	}
+	return f.Sync()
+}
+
+// Verify checks the hash chain of an audit log and returns the number of entries and the hash of the last
+// entry. It returns an error identifying the first entry that was modified, inserted, or removed. Entries
+// removed from the end of the log can only be detected by comparing the last hash with one recorded
+// elsewhere.
+//
+//goland:noinspection GoUnusedExportedFunction
+func Verify(filename string) (int, string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, "", fmt.Errorf("unable to open audit log: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	count, prevHash := 0, ""
+	for line := 1; scanner.Scan(); line++ {
+		var e Entry
+		if err = json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return count, prevHash, fmt.Errorf("line %d is not a valid entry: %w", line, err)
+		}
+		if e.Sequence != count+1 {
+			return count, prevHash, fmt.Errorf("line %d: expected entry %d, found entry %d", line, count+1, e.Sequence)
+		}
+		if e.PrevHash != prevHash {
+			return count, prevHash, fmt.Errorf("line %d: entry %d is not chained to the previous entry", line, e.Sequence)
+		}
+		hash, err := e.hash()
+		if err != nil {
+			return count, prevHash, err
+		}
+		if hash != e.Hash {
+			return count, prevHash, fmt.Errorf("line %d: entry %d was modified", line, e.Sequence)
+		}
+		count, prevHash = count+1, e.Hash
+	}
+	if err = scanner.Err(); err != nil {
+		return count, prevHash, fmt.Errorf("unable to read audit log: %w", err)
+	}
+	return count, prevHash, nil
+}
+
+// hash returns the hash of the JSON representation of an entry without its hash
+func (e Entry) hash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// lastEntry returns the last entry of a log file, or nil if the file is empty. The file is read backwards
+// from the end so that large logs are not read in full.
+func lastEntry(f *os.File) (*Entry, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	end := info.Size()
+
+	var line []byte
+	const chunk = 64 * 1024
+	for pos := end; pos > 0; {
+		n := min(int64(chunk), pos)
+		pos -= n
+		buf := make([]byte, n)
+		if _, err = f.ReadAt(buf, pos); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		line = append(buf, line...)
+
+		// The last line ends with a newline, so the line starts after the one before it
+		trimmed := bytes.TrimRight(line, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i != -1 {
+			line = trimmed[i+1:]
+			break
+		}
+		if pos == 0 {
+			line = trimmed
+		}
+	}
+	if len(bytes.TrimSpace(line)) == 0 {
+		return nil, nil
+	}
+
+	var e Entry
+	if err = json.Unmarshal(line, &e); err != nil {
+		return nil, fmt.Errorf("the last entry is not valid: %w", err)
+	}
+	return &e, nil
+}
+
+// lock creates the lock file of a log, so that entries appended by other processes are chained correctly.
+// It returns a function that removes the lock file.
+func lock(filename string) (func(), error) {
+	lockFile := filename + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lockFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_ = f.Close()
+			return func() {
+				_ = os.Remove(lockFile)
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("unable to lock audit log: %w", err)
+		}
+
+		// Remove a lock left over from a process that died
+		if info, err := os.Stat(lockFile); err == nil && time.Since(info.ModTime()) > lockStale {
+			_ = os.Remove(lockFile)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("unable to lock audit log: %s exists", lockFile)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// appendEntries appends count entries to a new log and returns its name
+func appendEntries(t *testing.T, count int) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range count {
+		if err = l.Append(Entry{
+			Time:       time.Now().UTC(),
+			RunID:      "run",
+			Step:       fmt.Sprintf("%d", i+1),
+			Task:       "aws_ec2_stop",
+			User:       "tester",
+			Parameters: map[string]any{"instance_ids": []any{fmt.Sprintf("i-%d", i)}, "count": i},
+			Success:    true,
+			Changed:    true,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filename
+}
+
+// TestVerify tests that Verify accepts an intact log and detects a modified, inserted, removed, or reordered
+// entry
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(lines [][]byte) [][]byte
+		wantErr string
+	}{
+		{"intact", func(lines [][]byte) [][]byte { return lines }, ""},
+		{"modified entry", func(lines [][]byte) [][]byte {
+			lines[1] = bytes.Replace(lines[1], []byte("i-1"), []byte("i-9"), 1)
+			return lines
+		}, "entry 2 was modified"},
+		{"modified outcome", func(lines [][]byte) [][]byte {
+			lines[2] = bytes.Replace(lines[2], []byte(`"success":true`), []byte(`"success":false`), 1)
+			return lines
+		}, "entry 3 was modified"},
+		{"inserted entry", func(lines [][]byte) [][]byte {
+			return append(lines[:2:2], append([][]byte{lines[1]}, lines[2:]...)...)
+		}, "expected entry 3, found entry 2"},
+		{"removed entry", func(lines [][]byte) [][]byte {
+			return append(lines[:1:1], lines[2:]...)
+		}, "expected entry 2, found entry 3"},
+		{"reordered entries", func(lines [][]byte) [][]byte {
+			lines[1], lines[2] = lines[2], lines[1]
+			return lines
+		}, "expected entry 2, found entry 3"},
+		{"renumbered entry", func(lines [][]byte) [][]byte {
+			// Entry 2 is removed and the following entries renumbered, so only the chain detects it
+			lines = append(lines[:1:1], lines[2:]...)
+			lines[1] = bytes.Replace(lines[1], []byte(`"sequence":3`), []byte(`"sequence":2`), 1)
+			return lines
+		}, "entry 2 is not chained to the previous entry"},
+		{"not an entry", func(lines [][]byte) [][]byte {
+			lines[3] = []byte("{")
+			return lines
+		}, "line 4 is not a valid entry"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := appendEntries(t, 4)
+			data, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := tt.modify(bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n")))
+			if err = os.WriteFile(filename, append(bytes.Join(lines, []byte("\n")), '\n'), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			count, _, err := Verify(filename)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != 4 {
+				t.Errorf("got %d entries, want 4", count)
+			}
+		})
+	}
+}
+
+// TestConcurrentAppend tests that entries appended at the same time through one log, as by background tasks,
+// and through several logs of the same file, as by several processes, are all chained
+func TestConcurrentAppend(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.jsonl")
+	var logs []*Log
+	for range 3 {
+		l, err := Open(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logs = append(logs, l)
+	}
+
+	const perLog = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, len(logs)*perLog)
+	for i, l := range logs {
+		for j := range perLog {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- l.Append(Entry{RunID: fmt.Sprintf("run-%d", i), Step: fmt.Sprintf("%d", j+1), Task: "test"})
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, last, err := Verify(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != len(logs)*perLog || last == "" {
+		t.Errorf("got %d entries, want %d", count, len(logs)*perLog)
+	}
+	if _, err = os.Stat(filename + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("the lock file was not removed: %v", err)
+	}
+}
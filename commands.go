@@ -0,0 +1,154 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/OpsBlade/OpsBlade/audit"
+	"github.com/OpsBlade/OpsBlade/shared"
+	"github.com/OpsBlade/OpsBlade/signature"
+)
+
+// commands are run instead of a workflow if the first argument is their name. They return the exit code.
+var commands = map[string]func(args []string) int{
+	"encrypt": encryptCommand,
+	"decrypt": decryptCommand,
+	"sign":    signCommand,
+	"verify":  verifyCommand,
+	"audit":   auditCommand,
+}
+
+// encryptCommand encrypts a file, such as a JSON secrets file, with the passphrase from OPSBLADE_KEY_FILE
+// or OPSBLADE_PASSPHRASE
+func encryptCommand(args []string) int {
+	if len(args) != 2 {
+		fmt.Printf("Use: %s encrypt <input> <output>\n", PROGNAME)
+		return 1
+	}
+	passphrase, err := shared.Passphrase("", "", nil)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	plaintext, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	data, err := shared.Encrypt(plaintext, passphrase)
+	if err == nil {
+		err = os.WriteFile(args[1], data, 0600)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// decryptCommand decrypts a file encrypted by encryptCommand or variables_save, writing it to stdout if no
+// output file is provided
+func decryptCommand(args []string) int {
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Printf("Use: %s decrypt <input> [output]\n", PROGNAME)
+		return 1
+	}
+	passphrase, err := shared.Passphrase("", "", nil)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	plaintext, err := shared.ReadEncryptedFile(args[0], passphrase)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	if len(args) == 1 {
+		_, _ = os.Stdout.Write(plaintext)
+		return 0
+	}
+	if err = os.WriteFile(args[1], plaintext, 0600); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// signCommand signs a workflow file and the child workflow files it includes with an ed25519 private key,
+// writing the signature next to the workflow file
+func signCommand(args []string) int {
+	if len(args) != 2 {
+		fmt.Printf("Use: %s sign <private-key> <workflow>\n", PROGNAME)
+		return 1
+	}
+	key, err := signature.LoadPrivateKey(args[0])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	s, err := signature.Sign(args[1], key)
+	if err == nil {
+		err = s.Write(args[1])
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Signed %s with key %s\n", args[1], s.KeyID)
+	for _, f := range s.Files[1:] {
+		fmt.Printf("  includes %s\n", f.Path)
+	}
+	return 0
+}
+
+// verifyCommand verifies the signature of a workflow file with the trusted keys in the file provided or
+// named by OPSBLADE_TRUSTED_KEYS
+func verifyCommand(args []string) int {
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Printf("Use: %s verify <workflow> [trusted-keys]\n", PROGNAME)
+		return 1
+	}
+	keyFile := os.Getenv(signature.TrustedKeysEnv)
+	if len(args) == 2 {
+		keyFile = args[1]
+	}
+	if keyFile == "" {
+		fmt.Printf("Error: no trusted keys, provide a file or set %s\n", signature.TrustedKeysEnv)
+		return 1
+	}
+	keys, err := signature.LoadPublicKeys(keyFile)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	verified, err := signature.Verify(args[0], data, keys)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Verified %s, signed with key %s, covering %d file(s)\n", args[0], verified.KeyID, len(verified.Files))
+	return 0
+}
+
+// auditCommand checks the hash chain of an audit log
+func auditCommand(args []string) int {
+	if len(args) != 2 || args[0] != "verify" {
+		fmt.Printf("Use: %s audit verify <audit-log>\n", PROGNAME)
+		return 1
+	}
+	count, last, err := audit.Verify(args[1])
+	if err != nil {
+		fmt.Printf("Error: %s: %v (%d entries verified)\n", args[1], err, count)
+		return 1
+	}
+	fmt.Printf("Verified %s: %d entries, last hash %s\n", args[1], count, last)
+	return 0
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package lock
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fileBackend keeps each lock in a file named after the lock, containing its holder. Locks are shared by
+// the processes that use the same directory, which may be on a shared file system.
+type fileBackend struct {
+	dir string
+}
+
+// newFileBackend returns a file backend for the directory in the lock settings
+func newFileBackend(cfg Config) (Backend, error) {
+	dir := cfg.Dir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return &fileBackend{dir: dir}, nil
+}
+
+// path returns the file of a lock. Characters that are not safe in a file name are replaced.
+func (b *fileBackend) path(name string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, name)
+	return filepath.Join(b.dir, "opsblade-"+safe+".lock")
+}
+
+// Acquire creates the lock file, replacing it if its holder has expired. The file is written under a
+// temporary name and linked to the name of the lock, so that it is never seen partially written.
+func (b *fileBackend) Acquire(name string, h Holder) (*Holder, error) {
+	path := b.path(name)
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	tmp := path + "." + h.ID
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.Remove(tmp)
+	}()
+
+	for {
+		err = os.Link(tmp, path)
+		if err == nil {
+			return nil, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		current, err := readHolder(path)
+		if os.IsNotExist(err) {
+			continue // Released in the meantime
+		}
+		if err != nil {
+			return nil, err
+		}
+		if time.Now().Before(current.Expires) {
+			return current, ErrHeld
+		}
+		if err = removeStale(path, current); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// removeStale removes a lock file whose holder has expired. The file is moved aside before it is removed,
+// so that a lock acquired by another process in the meantime is restored rather than removed.
+func removeStale(path string, stale *Holder) error {
+	aside := path + "." + newID()
+	if err := os.Rename(path, aside); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if moved, err := readHolder(aside); err == nil && moved.ID != stale.ID {
+		_ = os.Link(aside, path)
+	}
+	return os.Remove(aside)
+}
+
+// Refresh replaces the lock file with one with the new expiry if it is still held by the holder. The file is
+// moved aside before its holder is checked, so that a lock taken over by another process in the meantime is
+// restored rather than overwritten. The new file is linked to the name of the lock, which fails if another
+// process acquired the lock in the instant it was moved aside, so the lock is never held twice.
+func (b *fileBackend) Refresh(name string, h Holder) error {
+	path := b.path(name)
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	tmp := path + "." + newID()
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp)
+	}()
+
+	aside := path + "." + newID()
+	if err = os.Rename(path, aside); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("the lock was released")
+		}
+		return err
+	}
+	defer func() {
+		_ = os.Remove(aside)
+	}()
+	current, err := readHolder(aside)
+	if err != nil {
+		return err
+	}
+	if current.ID != h.ID {
+		_ = os.Link(aside, path)
+		return fmt.Errorf("the lock is held by %s", current.String())
+	}
+	if err = os.Link(tmp, path); err != nil {
+		if !os.IsExist(err) {
+			_ = os.Link(aside, path)
+			return err
+		}
+		if current, err = readHolder(path); err == nil {
+			return fmt.Errorf("the lock is held by %s", current.String())
+		}
+		return err
+	}
+	return nil
+}
+
+// Release removes the lock file if it is held by the holder
+func (b *fileBackend) Release(name string, h Holder) error {
+	path := b.path(name)
+	current, err := readHolder(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if current.ID != h.ID {
+		return fmt.Errorf("lock %s is held by %s", name, current.String())
+	}
+	return os.Remove(path)
+}
+
+// ForceRelease removes the lock file
+func (b *fileBackend) ForceRelease(name string) (*Holder, error) {
+	path := b.path(name)
+	current, err := readHolder(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err = os.Remove(path); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// readHolder reads the holder of a lock file. A file that cannot be parsed is treated as a lock that
+// expired long ago.
+func readHolder(path string) (*Holder, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var h Holder
+	if err = json.Unmarshal(data, &h); err != nil {
+		return &Holder{User: "unknown", Host: "unknown"}, nil
+	}
+	return &h, nil
+}
+
+// newID returns a random ID
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
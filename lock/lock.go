@@ -0,0 +1,218 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+// Package lock prevents concurrent runs of a workflow with an exclusive, named lock. Locks are kept by a
+// backend, a lock file by default, and expire if they are not refreshed, so that a lock left by a process
+// that died does not block later runs.
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// Defaults
+const (
+	DefaultTTL     = 600    // Seconds a lock is held without being refreshed
+	DefaultBackend = "file" // Lock files in the temporary directory
+)
+
+// pollInterval is how often a held lock is tried again while waiting for it
+const pollInterval = time.Second
+
+// ErrHeld is returned by a backend if the lock is held by another holder
+var ErrHeld = errors.New("lock is held")
+
+// Config contains the lock settings of a workflow
+type Config struct {
+	Name    string `yaml:"name" json:"name"`       // Lock name, the workflow name by default
+	TTL     int    `yaml:"ttl" json:"ttl"`         // Seconds the lock is held without being refreshed
+	Wait    int    `yaml:"wait" json:"wait"`       // Seconds to wait for a held lock, 0 fails immediately
+	Backend string `yaml:"backend" json:"backend"` // Backend that keeps the lock, file by default
+	Dir     string `yaml:"dir" json:"dir"`         // Directory of lock files, the temporary directory by default
+}
+
+// Holder describes the holder of a lock
+type Holder struct {
+	ID       string    `json:"id"`                 // Unique ID of the holder
+	User     string    `json:"user"`               // Operating system user
+	Host     string    `json:"host"`               // Host name
+	PID      int       `json:"pid"`                // Process ID
+	Workflow string    `json:"workflow,omitempty"` // Workflow file
+	Acquired time.Time `json:"acquired"`           // Time the lock was acquired
+	Expires  time.Time `json:"expires"`            // Time the lock expires unless it is refreshed
+}
+
+// String returns a human-readable description of the holder
+func (h *Holder) String() string {
+	return fmt.Sprintf("%s@%s (pid %d) since %s", h.User, h.Host, h.PID, h.Acquired.Format(time.RFC3339))
+}
+
+// Backend keeps locks. Backends other than the file backend, such as a remote store, are registered with
+// RegisterBackend.
+type Backend interface {
+	// Acquire acquires a lock for the holder if it is free or has expired. If the lock is held, it returns
+	// the current holder and ErrHeld.
+	Acquire(name string, h Holder) (*Holder, error)
+
+	// Refresh extends the expiry of a lock held by the holder. It fails if the lock is no longer held by
+	// the holder.
+	Refresh(name string, h Holder) error
+
+	// Release releases a lock held by the holder
+	Release(name string, h Holder) error
+
+	// ForceRelease releases a lock regardless of its holder and returns the holder, or nil if the lock
+	// was not held
+	ForceRelease(name string) (*Holder, error)
+}
+
+var (
+	backendsMu sync.RWMutex
+	backends   = make(map[string]func(Config) (Backend, error))
+)
+
+func init() {
+	RegisterBackend(DefaultBackend, newFileBackend)
+}
+
+// RegisterBackend registers a lock backend. The factory receives the lock settings of the workflow.
+//
+//goland:noinspection GoUnusedExportedFunction
+func RegisterBackend(name string, factory func(Config) (Backend, error)) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	backends[name] = factory
+}
+
+// Lock is an acquired lock. It is refreshed in the background until it is released.
+type Lock struct {
+	Name    string
+	backend Backend
+	holder  Holder
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// backend returns the backend of a configuration
+func (cfg Config) backend() (Backend, error) {
+	name := cfg.Backend
+	if name == "" {
+		name = DefaultBackend
+	}
+	backendsMu.RLock()
+	factory, ok := backends[name]
+	backendsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown lock backend %s", name)
+	}
+	return factory(cfg)
+}
+
+// Validate checks the lock settings
+func (cfg Config) Validate() error {
+	if cfg.Name == "" {
+		return fmt.Errorf("lock name is required")
+	}
+	if cfg.TTL < 0 || cfg.Wait < 0 {
+		return fmt.Errorf("lock ttl and wait must not be negative")
+	}
+	_, err := cfg.backend()
+	return err
+}
+
+// Acquire acquires a lock. If the lock is held, it waits up to the configured number of seconds for it to
+// be released or to expire, calling waiting once with the current holder. The lock is refreshed in the
+// background until it is released, and lost is called if it cannot be refreshed.
+//
+//goland:noinspection GoUnusedExportedFunction
+func Acquire(cfg Config, workflow string, waiting func(*Holder), lost func(error)) (*Lock, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	backend, err := cfg.backend()
+	if err != nil {
+		return nil, err
+	}
+	ttl := time.Duration(cfg.TTL) * time.Second
+	if ttl == 0 {
+		ttl = DefaultTTL * time.Second
+	}
+
+	host, _ := os.Hostname()
+	h := Holder{ID: newID(), User: shared.CurrentUser(), Host: host, PID: os.Getpid(), Workflow: workflow}
+	deadline := time.Now().Add(time.Duration(cfg.Wait) * time.Second)
+	notified := false
+	for {
+		h.Acquired = time.Now().UTC()
+		h.Expires = h.Acquired.Add(ttl)
+		current, err := backend.Acquire(cfg.Name, h)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrHeld) {
+			return nil, fmt.Errorf("unable to acquire lock %s: %w", cfg.Name, err)
+		}
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("lock %s is held by %s", cfg.Name, current.String())
+		}
+		if !notified && waiting != nil {
+			waiting(current)
+			notified = true
+		}
+		time.Sleep(min(pollInterval, time.Until(deadline)))
+	}
+
+	l := &Lock{Name: cfg.Name, backend: backend, holder: h, stop: make(chan struct{}), done: make(chan struct{})}
+	go l.refresh(ttl, lost)
+	return l, nil
+}
+
+// refresh extends the expiry of the lock every third of its time to live until it is released
+func (l *Lock) refresh(ttl time.Duration, lost func(error)) {
+	defer close(l.done)
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			h := l.holder
+			h.Expires = time.Now().UTC().Add(ttl)
+			if err := l.backend.Refresh(l.Name, h); err != nil {
+				if lost != nil {
+					lost(fmt.Errorf("lock %s was lost: %w", l.Name, err))
+				}
+				return
+			}
+		}
+	}
+}
+
+// Release stops refreshing the lock and releases it
+func (l *Lock) Release() error {
+	close(l.stop)
+	<-l.done
+	return l.backend.Release(l.Name, l.holder)
+}
+
+// ForceUnlock releases a lock regardless of its holder, e.g. after a run was interrupted in a way that
+// left the lock behind. It returns the holder, or nil if the lock was not held.
+//
+//goland:noinspection GoUnusedExportedFunction
+func ForceUnlock(cfg Config) (*Holder, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	backend, err := cfg.backend()
+	if err != nil {
+		return nil, err
+	}
+	return backend.ForceRelease(cfg.Name)
+}
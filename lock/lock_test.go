@@ -0,0 +1,237 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package lock
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testConfig returns the settings of a lock in a new directory
+func testConfig(t *testing.T) Config {
+	return Config{Name: "test", Dir: t.TempDir()}
+}
+
+// writeHolder writes a lock file with the holder, as left by another process
+func writeHolder(t *testing.T, cfg Config, h Holder) {
+	t.Helper()
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile((&fileBackend{dir: cfg.Dir}).path(cfg.Name), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// currentHolder reads the holder of the lock file
+func currentHolder(t *testing.T, cfg Config) *Holder {
+	t.Helper()
+	h, err := readHolder((&fileBackend{dir: cfg.Dir}).path(cfg.Name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+// TestAcquire tests that a held lock is refused, and that a lock that expired or cannot be read is taken over
+func TestAcquire(t *testing.T) {
+	tests := []struct {
+		name    string
+		holder  *Holder // Lock file left by another process
+		garbage bool    // The lock file cannot be parsed
+		wantErr string
+	}{
+		{"free", nil, false, ""},
+		{"held", &Holder{ID: "other", User: "alice", Host: "ops1", Expires: time.Now().Add(time.Hour)}, false, "held by alice@ops1"},
+		{"stale", &Holder{ID: "other", User: "alice", Host: "ops1", Expires: time.Now().Add(-time.Second)}, false, ""},
+		{"unreadable", nil, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			if tt.holder != nil {
+				writeHolder(t, cfg, *tt.holder)
+			}
+			if tt.garbage {
+				if err := os.WriteFile((&fileBackend{dir: cfg.Dir}).path(cfg.Name), []byte("{"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			l, err := Acquire(cfg, "workflow.yaml", nil, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				if current := currentHolder(t, cfg); current.ID != tt.holder.ID {
+					t.Errorf("the lock of %s was replaced by %s", tt.holder.ID, current.ID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if current := currentHolder(t, cfg); current.ID != l.holder.ID || current.Workflow != "workflow.yaml" {
+				t.Errorf("the lock file does not name the holder: %+v", current)
+			}
+
+			// The lock cannot be acquired twice, and is free again once released
+			if _, err = Acquire(cfg, "workflow.yaml", nil, nil); err == nil {
+				t.Error("the lock was acquired twice")
+			}
+			if err = l.Release(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = os.Stat((&fileBackend{dir: cfg.Dir}).path(cfg.Name)); !os.IsNotExist(err) {
+				t.Errorf("the lock file was not removed: %v", err)
+			}
+		})
+	}
+}
+
+// TestWait tests that Acquire waits for a held lock to be released, reporting the holder once, and gives up
+// after the wait
+func TestWait(t *testing.T) {
+	cfg := testConfig(t)
+	first, err := Acquire(cfg, "", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		_ = first.Release()
+	}()
+
+	cfg.Wait = 5
+	var waited []*Holder
+	second, err := Acquire(cfg, "", func(h *Holder) { waited = append(waited, h) }, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(waited) != 1 || waited[0].ID != first.holder.ID {
+		t.Errorf("expected one wait for %s, got %v", first.holder.ID, waited)
+	}
+
+	cfg.Wait = 1
+	start := time.Now()
+	if _, err = Acquire(cfg, "", nil, nil); err == nil || !strings.Contains(err.Error(), "is held by") {
+		t.Errorf("expected the lock to be held, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("gave up after %s, before the wait", elapsed)
+	}
+	if err = second.Release(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestForceUnlock tests that a forced unlock releases the lock of another holder, that the holder then
+// reports the lock lost, and that it does not release the lock of the next holder
+func TestForceUnlock(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.TTL = 1
+	lost := make(chan error, 1)
+	first, err := Acquire(cfg, "", nil, func(err error) { lost <- err })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	holder, err := ForceUnlock(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holder == nil || holder.ID != first.holder.ID {
+		t.Fatalf("expected the holder %s, got %+v", first.holder.ID, holder)
+	}
+	second, err := Acquire(cfg, "", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err = <-lost:
+		if !strings.Contains(err.Error(), "lock test was lost") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("the lock was not reported lost")
+	}
+	if current := currentHolder(t, cfg); current.ID != second.holder.ID {
+		t.Errorf("the refresh of the lost lock replaced the lock of %s with %s", second.holder.ID, current.ID)
+	}
+	if err = first.Release(); err == nil {
+		t.Error("the lost lock was released")
+	}
+	if err = second.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	if holder, err = ForceUnlock(cfg); err != nil || holder != nil {
+		t.Errorf("expected no holder of a free lock, got %+v, %v", holder, err)
+	}
+}
+
+// TestRefresh tests that the file backend only refreshes a lock that is still held by the holder, and never
+// replaces the lock of another holder
+func TestRefresh(t *testing.T) {
+	b := &fileBackend{dir: t.TempDir()}
+	mine := Holder{ID: "mine", User: "bob", Expires: time.Now().Add(time.Minute)}
+	theirs := Holder{ID: "theirs", User: "alice", Expires: time.Now().Add(time.Minute)}
+
+	if _, err := b.Acquire("test", mine); err != nil {
+		t.Fatal(err)
+	}
+	mine.Expires = mine.Expires.Add(time.Hour)
+	if err := b.Refresh("test", mine); err != nil {
+		t.Fatal(err)
+	}
+	if current, err := readHolder(b.path("test")); err != nil || !current.Expires.Equal(mine.Expires) {
+		t.Errorf("the expiry was not refreshed: %+v, %v", current, err)
+	}
+
+	// Another holder takes the lock over, e.g. after a forced unlock
+	if _, err := b.ForceRelease("test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Refresh("test", mine); err == nil {
+		t.Error("a released lock was refreshed")
+	}
+	if _, err := b.Acquire("test", theirs); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Refresh("test", mine); err == nil || !strings.Contains(err.Error(), "held by alice") {
+		t.Errorf("expected the lock to be held by alice, got %v", err)
+	}
+	if current, err := readHolder(b.path("test")); err != nil || current.ID != theirs.ID {
+		t.Errorf("the lock of another holder was replaced: %+v, %v", current, err)
+	}
+
+	// Refreshes by both holders at the same time leave the lock with its holder. A refresh by the holder may
+	// fail while the other moves the file aside, which only happens in this test.
+	var wg sync.WaitGroup
+	for _, h := range []Holder{mine, theirs, mine, theirs} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				_ = b.Refresh("test", h)
+			}
+		}()
+	}
+	wg.Wait()
+	if current, err := readHolder(b.path("test")); err != nil || current.ID != theirs.ID {
+		t.Errorf("the lock of another holder was replaced: %+v, %v", current, err)
+	}
+	if err := b.Release("test", mine); err == nil {
+		t.Error("the lock of another holder was released")
+	}
+	if err := b.Release("test", theirs); err != nil {
+		t.Fatal(err)
+	}
+}
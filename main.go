@@ -4,11 +4,15 @@
 package main
 
 import (
-	"fmt"
+	"crypto/ed25519"
 	"os"
 
 	"github.com/spf13/pflag"
 
+	"github.com/OpsBlade/OpsBlade/policy"
+	"github.com/OpsBlade/OpsBlade/report"
+	"github.com/OpsBlade/OpsBlade/shared"
+	"github.com/OpsBlade/OpsBlade/signature"
 	"github.com/OpsBlade/OpsBlade/workflow"
 )
 
@@ -21,62 +25,200 @@ const (
 // This program serves both as a CLI to execute workflows from a YAML file or stdin, and as an example of
 // how to use the workflow package.
 func main() {
+	// Commands such as "encrypt" are run instead of a workflow
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+
 	var stdin bool
 	var dryrun bool
 	var json bool
+	var output string
 	var debug bool
+	var reports []string
+	var step bool
+	var plan bool
+	var readOnly bool
+	var policyFile string
+	var confirmAccounts []string
+	var requireSignature bool
+	var trustedKeysFile string
+	var auditLog string
+	var forceUnlock bool
+	var environment string
 
 	// Use the pflag package to parse command line arguments
 	pflag.BoolVarP(&dryrun, "dryrun", "d", false, "Dry run")
 	pflag.BoolVarP(&stdin, "stdin", "s", false, "Read from stdin")
 	pflag.BoolVarP(&json, "json", "j", false, "Output JSON")
+	pflag.StringVarP(&output, "output", "o", "", "Output mode: text, json, or ndjson")
 	pflag.BoolVarP(&debug, "debug", "v", false, "Debug mode")
+	pflag.BoolVarP(&plan, "plan", "p", false, "Plan mode: show the changes tasks would make")
+	pflag.BoolVar(&step, "step", false, "Ask before running each task")
+	pflag.BoolVar(&readOnly, "read-only", false, "Refuse to run tasks that change something")
+	pflag.StringVar(&policyFile, "policy", "", "Policy file with rules every task must satisfy")
+	pflag.StringArrayVar(&confirmAccounts, "confirm-account", nil, "Confirm an AWS account that aws_guard requires to be confirmed, may be repeated")
+	pflag.BoolVar(&requireSignature, "require-signature", false, "Refuse workflow files that are not signed by a trusted key")
+	pflag.StringVar(&trustedKeysFile, "trusted-keys", os.Getenv(signature.TrustedKeysEnv), "File of trusted public keys for --require-signature")
+	pflag.StringVar(&auditLog, "audit-log", "", "Record every task that changes something in an audit log file")
+	pflag.StringVarP(&environment, "environment", "e", "", "Select one of the environments defined in the workflow")
+	pflag.BoolVar(&forceUnlock, "force-unlock", false, "Release the workflow's lock regardless of its holder, and exit")
+	pflag.StringArrayVarP(&reports, "report", "r", nil, "Write a run report (junit=path, markdown=path, or plan=path), may be repeated")
 	pflag.Usage = usage
 	pflag.Parse()
 
-	fmt.Printf("%s v%s\n\n", PROGNAME, VERSION)
+	// In NDJSON mode stdout is reserved for events, so all human-readable output, including the errors
+	// below, goes to stderr. An invalid output mode is reported to stderr as well.
+	switch output {
+	case workflow.OutputNDJSON:
+		shared.Console = os.Stderr
+	case "", workflow.OutputText, workflow.OutputJSON:
+	default:
+		shared.Console = os.Stderr
+		shared.Printf("Error: Invalid output mode '%s'\n", output)
+		usage()
+		os.Exit(1)
+	}
+
+	// Validate report specifications before running anything
+	var reportSpecs []report.Spec
+	for _, r := range reports {
+		spec, err := report.ParseSpec(r)
+		if err != nil {
+			shared.Printf("Error: %v\n", err)
+			usage()
+			os.Exit(1)
+		}
+		reportSpecs = append(reportSpecs, spec)
+	}
 
 	// Require stdin or a filename, but not both
 	var yamlFilename = ""
 	if stdin {
 		if len(pflag.Args()) > 0 {
-			fmt.Println("Error: Cannot use both -stdin and a filename argument")
+			shared.Println("Error: Cannot use both -stdin and a filename argument")
 			usage()
 			os.Exit(1)
 		}
 	} else {
 		if len(pflag.Args()) < 1 {
-			fmt.Println("Error: Either a filename or --stdin must be provided")
+			shared.Println("Error: Either a filename or --stdin must be provided")
 			usage()
 			os.Exit(1)
 		}
 		yamlFilename = pflag.Arg(0)
 	}
 
+	// Plan mode executes read-only tasks for real, which a dry run would prevent
+	if plan && dryrun {
+		shared.Println("Error: Cannot use both --plan and --dryrun")
+		usage()
+		os.Exit(1)
+	}
+
+	// Step mode reads the operator's answers from stdin
+	if step && (stdin || !shared.IsTerminal()) {
+		shared.Println("Error: --step requires an interactive terminal and cannot be used with --stdin")
+		usage()
+		os.Exit(1)
+	}
+
+	// Load the policy before running anything
+	var rules *policy.Policy
+	if policyFile != "" {
+		var err error
+		if rules, err = policy.Load(policyFile); err != nil {
+			shared.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Load the trusted keys before reading the workflow
+	var trustedKeys []ed25519.PublicKey
+	if requireSignature {
+		if trustedKeysFile == "" {
+			shared.Printf("Error: --require-signature requires --trusted-keys or %s\n", signature.TrustedKeysEnv)
+			os.Exit(1)
+		}
+		var err error
+		if trustedKeys, err = signature.LoadPublicKeys(trustedKeysFile); err != nil {
+			shared.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Create a new workflow
 	w := workflow.New(
 		workflow.WithJSON(json),
+		workflow.WithOutput(output),
 		workflow.WithDryRun(dryrun),
-		workflow.WithDebug(debug))
+		workflow.WithDebug(debug),
+		workflow.WithStep(step),
+		workflow.WithPlan(plan),
+		workflow.WithReadOnly(readOnly),
+		workflow.WithPolicy(rules),
+		workflow.WithConfirmedAccounts(confirmAccounts),
+		workflow.WithTrustedKeys(trustedKeys),
+		workflow.WithAuditLog(auditLog),
+		workflow.WithEnvironment(environment))
 
 	// Load the workflow. If the string is empty, Load will read from stdin
 	err := w.Load(yamlFilename)
+
+	// The output mode may also be set in the workflow file, so it is checked again after loading
+	if w.OutputMode() == workflow.OutputNDJSON {
+		shared.Console = os.Stderr
+	}
+
+	shared.Printf("%s v%s\n\n", PROGNAME, VERSION)
+
 	if err != nil {
-		fmt.Printf("Error: Unable to load tasks: %v\n", err)
+		shared.Printf("Error: Unable to load tasks: %v\n", err)
 		os.Exit(1)
 	}
 
+	// Release a lock left behind by an interrupted run instead of running the workflow
+	if forceUnlock {
+		holder, err := w.ForceUnlock()
+		switch {
+		case err != nil:
+			shared.Printf("Error: %v\n", err)
+			os.Exit(1)
+		case holder == nil:
+			shared.Println("The workflow's lock is not held.")
+		default:
+			shared.Printf("Released the workflow's lock held by %s\n", holder.String())
+		}
+		os.Exit(0)
+	}
+
 	// Execute the workflow
 	result := w.Execute()
+
+	// Write any requested reports
+	for _, spec := range reportSpecs {
+		if err = report.Write(spec, w.Summary()); err != nil {
+			shared.Printf("Error: Unable to write %s report: %v\n", spec.Format, err)
+			result = false
+		}
+	}
+
 	if result {
-		fmt.Println("All tasks complete. Exiting with code 0.")
+		shared.Println("All tasks complete. Exiting with code 0.")
 		os.Exit(0)
 	}
-	fmt.Println("Terminating due to failed task. Exiting with code 1.")
+	shared.Println("Terminating due to failed task. Exiting with code 1.")
 	os.Exit(1)
 }
 
 // usage prints the usage message
 func usage() {
-	fmt.Printf("\nUse: %s [filename.yaml] [--stdin] [--json] [--dryrun] [--debug]\n", PROGNAME)
+	shared.Printf("\nUse: %s [filename.yaml] [--stdin] [--json] [--output text|json|ndjson] [--report format=path] [--dryrun] [--plan] [--read-only] [--policy file] [--confirm-account id] [--require-signature] [--trusted-keys file] [--audit-log file] [--environment name] [--force-unlock] [--step] [--debug]\n", PROGNAME)
+	shared.Printf("     %s encrypt <input> <output>\n", PROGNAME)
+	shared.Printf("     %s decrypt <input> [output]\n", PROGNAME)
+	shared.Printf("     %s sign <private-key> <workflow>\n", PROGNAME)
+	shared.Printf("     %s verify <workflow> [trusted-keys]\n", PROGNAME)
+	shared.Printf("     %s audit verify <audit-log>\n", PROGNAME)
 }
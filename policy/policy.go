@@ -0,0 +1,168 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+// Package policy evaluates guardrail rules against each task before it is executed. Rules are loaded from
+// a YAML file and use the same select criteria as exit_if and failed_when.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// Policy is a set of rules that every task must satisfy
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule applies to the tasks listed in Tasks (all tasks if empty) when all When criteria match. A task it
+// applies to violates the rule if Deny is set, or if any of the Require criteria does not match.
+type Rule struct {
+	Name        string                  `json:"name"`
+	Description string                  `json:"description,omitempty"`
+	Tasks       []string                `json:"tasks,omitempty"`   // Task types the rule applies to
+	When        []shared.SelectCriteria `json:"when,omitempty"`    // Conditions under which the rule applies
+	Require     []shared.SelectCriteria `json:"require,omitempty"` // Conditions the task must meet
+	Deny        bool                    `json:"deny,omitempty"`    // The task is not allowed at all
+}
+
+// Input is what a task is evaluated against. Rules refer to it with fields such as task.skip_matching,
+// workflow.tags, and target.tags.Env.
+type Input struct {
+	Task     map[string]any                 // Task instructions with variables resolved
+	Workflow map[string]any                 // Workflow name, file, tags, env, and dryrun
+	Describe func() (map[string]any, error) // Describes the task's target, nil if the task cannot
+}
+
+// Violation identifies the rule a task violates
+type Violation struct {
+	Rule        string `json:"rule"`
+	Description string `json:"description,omitempty"`
+}
+
+// Error returns a description of the violation
+func (v *Violation) Error() string {
+	if v.Description == "" {
+		return fmt.Sprintf("violates policy rule %s", v.Rule)
+	}
+	return fmt.Sprintf("violates policy rule %s: %s", v.Rule, v.Description)
+}
+
+// Load reads and validates a policy file
+//
+//goland:noinspection GoUnusedExportedFunction
+func Load(filename string) (*Policy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read policy file: %w", err)
+	}
+
+	// The rules are round-tripped through JSON so that numbers are float64, matching the representation of
+	// the instructions they are compared with
+	var raw any
+	if err = yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("policy deserialization error: %w", err)
+	}
+	if data, err = json.Marshal(raw); err != nil {
+		return nil, fmt.Errorf("policy deserialization error: %w", err)
+	}
+	var p Policy
+	if err = json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("policy deserialization error: %w", err)
+	}
+
+	if err = p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Validate checks the rules of the policy
+func (p *Policy) Validate() error {
+	names := make(map[string]bool)
+	for i, rule := range p.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("policy rule %d has no name", i+1)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate policy rule name: %s", rule.Name)
+		}
+		names[rule.Name] = true
+
+		if rule.Deny == (len(rule.Require) > 0) {
+			return fmt.Errorf("policy rule %s: exactly one of deny and require must be set", rule.Name)
+		}
+		for _, c := range slices.Concat(rule.When, rule.Require) {
+			if c.Field == "" {
+				return fmt.Errorf("policy rule %s: criteria field is missing", rule.Name)
+			}
+			if !shared.IsValidComparisonOperator(c.Compare) {
+				return fmt.Errorf("policy rule %s: invalid comparison operator: %s", rule.Name, c.Compare)
+			}
+		}
+	}
+	return nil
+}
+
+// Evaluate returns the first rule the task violates, or nil. The task's target is only described if a
+// rule that applies to the task refers to it. If it cannot be described, criteria that refer to it do
+// not match. An error is returned if the target cannot be described or the criteria cannot be applied.
+func (p *Policy) Evaluate(taskType string, in Input) (*Violation, error) {
+	document := map[string]any{"task": in.Task, "workflow": in.Workflow}
+	described := false
+
+	for _, rule := range p.Rules {
+		if len(rule.Tasks) > 0 && !slices.Contains(rule.Tasks, taskType) {
+			continue
+		}
+
+		// Describe the target the first time a rule needs it
+		if !described && refersToTarget(rule) {
+			described = true
+			if in.Describe != nil {
+				target, err := in.Describe()
+				if err != nil {
+					return nil, fmt.Errorf("unable to describe the target of the task for policy rule %s: %w", rule.Name, err)
+				}
+				document["target"] = target
+			}
+		}
+
+		applies, err := shared.ApplySelectionCriteria(document, rule.When)
+		if err != nil {
+			return nil, fmt.Errorf("policy rule %s: %w", rule.Name, err)
+		}
+		if !applies {
+			continue
+		}
+
+		if rule.Deny {
+			return &Violation{Rule: rule.Name, Description: rule.Description}, nil
+		}
+		met, err := shared.ApplySelectionCriteria(document, rule.Require)
+		if err != nil {
+			return nil, fmt.Errorf("policy rule %s: %w", rule.Name, err)
+		}
+		if !met {
+			return &Violation{Rule: rule.Name, Description: rule.Description}, nil
+		}
+	}
+	return nil, nil
+}
+
+// refersToTarget returns true if any criteria of the rule refer to the task's target
+func refersToTarget(rule Rule) bool {
+	for _, c := range slices.Concat(rule.When, rule.Require) {
+		if c.Field == "target" || strings.HasPrefix(c.Field, "target.") {
+			return true
+		}
+	}
+	return false
+}
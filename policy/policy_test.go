@@ -0,0 +1,156 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPolicy = `rules:
+  - name: prod-refresh-skip-matching
+    description: ASG refreshes in prod must skip matching instances
+    tasks: [aws_asg_refresh]
+    when:
+      - field: workflow.tags
+        compare: contains
+        value: prod
+    require:
+      - field: task.skip_matching
+        compare: equal
+        value: true
+  - name: no-cmd-in-prod
+    tasks: [cmd_exec]
+    when:
+      - field: workflow.tags
+        compare: contains
+        value: prod
+    deny: true
+  - name: stop-dev-only
+    description: Only dev instances may be stopped
+    tasks: [aws_ec2_instance_stop]
+    require:
+      - field: target.tags.Env
+        compare: equal
+        value: dev
+`
+
+// loadPolicy writes a policy file and loads it
+func loadPolicy(t *testing.T, content string) (*Policy, error) {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return Load(filename)
+}
+
+// target returns a Describe function that describes an instance with the Env tag, and counts its calls
+func target(env string, calls *int) func() (map[string]any, error) {
+	return func() (map[string]any, error) {
+		*calls++
+		return map[string]any{"instance_id": "i-0123", "tags": map[string]any{"Env": env}}, nil
+	}
+}
+
+// TestEvaluate tests that a task is checked against the rules that apply to it, and that the target of a
+// task is only described for rules that refer to it
+func TestEvaluate(t *testing.T) {
+	p, err := loadPolicy(t, testPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prod := map[string]any{"name": "deploy", "tags": []any{"prod"}}
+	dev := map[string]any{"name": "deploy", "tags": []any{"dev"}}
+
+	tests := []struct {
+		name      string
+		taskType  string
+		task      map[string]any
+		workflow  map[string]any
+		env       string // Env tag of the target, empty if the task cannot describe it
+		wantRule  string // Rule that is violated, empty if none
+		describes int    // Expected number of calls to Describe
+	}{
+		{"refresh skipping matching in prod", "aws_asg_refresh", map[string]any{"skip_matching": true}, prod, "", "", 0},
+		{"refresh not skipping matching in prod", "aws_asg_refresh", map[string]any{"skip_matching": false}, prod,
+			"", "prod-refresh-skip-matching", 0},
+		{"refresh without skip_matching in prod", "aws_asg_refresh", map[string]any{}, prod,
+			"", "prod-refresh-skip-matching", 0},
+		{"refresh not skipping matching in dev", "aws_asg_refresh", map[string]any{"skip_matching": false}, dev, "", "", 0},
+		{"command in prod", "cmd_exec", map[string]any{"cmd": "true"}, prod, "", "no-cmd-in-prod", 0},
+		{"command in dev", "cmd_exec", map[string]any{"cmd": "true"}, dev, "", "", 0},
+		{"command in untagged workflow", "cmd_exec", map[string]any{"cmd": "true"}, map[string]any{"name": "deploy"},
+			"", "", 0},
+		{"stop dev instance", "aws_ec2_instance_stop", map[string]any{"instance_id": "i-0123"}, prod, "dev", "", 1},
+		{"stop prod instance", "aws_ec2_instance_stop", map[string]any{"instance_id": "i-0123"}, dev,
+			"prod", "stop-dev-only", 1},
+		{"stop without description", "aws_ec2_instance_stop", map[string]any{"instance_id": "i-0123"}, dev,
+			"", "stop-dev-only", 0},
+		{"start prod instance", "aws_ec2_instance_start", map[string]any{"instance_id": "i-0123"}, prod, "prod", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			in := Input{Task: tt.task, Workflow: tt.workflow}
+			if tt.env != "" {
+				in.Describe = target(tt.env, &calls)
+			}
+
+			violation, err := p.Evaluate(tt.taskType, in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantRule == "" {
+				if violation != nil {
+					t.Errorf("unexpected violation: %v", violation)
+				}
+			} else if violation == nil || violation.Rule != tt.wantRule {
+				t.Errorf("expected a violation of %s, got %v", tt.wantRule, violation)
+			}
+			if calls != tt.describes {
+				t.Errorf("the target was described %d times, want %d", calls, tt.describes)
+			}
+		})
+	}
+
+	// A target that cannot be described fails the evaluation
+	_, err = p.Evaluate("aws_ec2_instance_stop", Input{
+		Task:     map[string]any{"instance_id": "i-0123"},
+		Workflow: dev,
+		Describe: func() (map[string]any, error) { return nil, errors.New("access denied") },
+	})
+	if err == nil || !strings.Contains(err.Error(), "stop-dev-only") || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("expected the description to fail, got %v", err)
+	}
+}
+
+// TestLoad tests that invalid rules are refused when the policy is loaded
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"no name", "rules:\n  - deny: true\n", "policy rule 1 has no name"},
+		{"duplicate name", "rules:\n  - name: a\n    deny: true\n  - name: a\n    deny: true\n", "duplicate policy rule name: a"},
+		{"neither deny nor require", "rules:\n  - name: a\n", "exactly one of deny and require"},
+		{"deny and require", "rules:\n  - name: a\n    deny: true\n    require:\n      - field: task.x\n        compare: equal\n        value: 1\n",
+			"exactly one of deny and require"},
+		{"missing field", "rules:\n  - name: a\n    deny: true\n    when:\n      - compare: equal\n        value: 1\n", "criteria field is missing"},
+		{"invalid operator", "rules:\n  - name: a\n    deny: true\n    when:\n      - field: task.x\n        compare: resembles\n",
+			"invalid comparison operator: resembles"},
+		{"not yaml", "rules: [", "policy deserialization error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadPolicy(t, tt.content); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
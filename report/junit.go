@@ -0,0 +1,119 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	ID         string          `xml:"id,attr,omitempty"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Body    string `xml:",chardata"`
+}
+
+// JUnit writes the run as a JUnit XML test suite with one test case per task
+func JUnit(out io.Writer, run shared.RunSummary) error {
+	suite := junitTestSuite{
+		Name: run.Name,
+		ID:   run.RunID,
+		Time: seconds(run.Duration()),
+		Properties: []junitProperty{
+			{Name: "run_id", Value: run.RunID},
+			{Name: "success", Value: fmt.Sprintf("%t", run.Success)},
+		},
+	}
+	if !run.StartTime.IsZero() {
+		suite.Timestamp = run.StartTime.UTC().Format(time.RFC3339)
+	}
+
+	for _, r := range run.Results {
+		tc := junitTestCase{
+			Name:      taskLabel(r),
+			ClassName: "opsblade." + run.Name,
+			Time:      seconds(r.Duration()),
+		}
+
+		switch r.Status() {
+		case "skipped", "cancelled":
+			tc.Skipped = &junitMessage{Message: r.Msg}
+			suite.Skipped++
+		case "failed":
+			tc.Failure = &junitMessage{Message: firstLine(r.Msg), Body: r.Msg}
+			suite.Failures++
+		}
+
+		if len(r.Data) > 0 {
+			tc.SystemOut = shared.AnyToYAMLIndent(r.Data, "", 2)
+		}
+
+		suite.TestCases = append(suite.TestCases, tc)
+		suite.Tests++
+	}
+
+	suites := junitTestSuites{
+		Name:     run.Name,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return fmt.Errorf("unable to encode JUnit report: %w", err)
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
+
+// seconds formats a duration as fractional seconds as expected by JUnit consumers
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
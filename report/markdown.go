@@ -0,0 +1,76 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// Markdown writes the run as a Markdown summary table suitable for attaching to a ticket
+func Markdown(out io.Writer, run shared.RunSummary) error {
+	var b strings.Builder
+
+	status := "Succeeded"
+	if !run.Success {
+		status = "Failed"
+	}
+
+	b.WriteString(fmt.Sprintf("# OpsBlade run: %s\n\n", mdEscape(run.Name)))
+	b.WriteString(fmt.Sprintf("* **Run ID:** %s\n", run.RunID))
+	b.WriteString(fmt.Sprintf("* **Status:** %s\n", status))
+	if !run.StartTime.IsZero() {
+		b.WriteString(fmt.Sprintf("* **Started:** %s\n", run.StartTime.UTC().Format(time.RFC3339)))
+	}
+	b.WriteString(fmt.Sprintf("* **Duration:** %s\n\n", run.Duration().Round(time.Millisecond)))
+
+	b.WriteString("| # | Name | Task | Status | Duration | Message |\n")
+	b.WriteString("|---|------|------|--------|----------|---------|\n")
+	for _, r := range run.Results {
+		b.WriteString(fmt.Sprintf("| %s | %s | `%s` | %s | %s | %s |\n",
+			r.Step(),
+			mdEscape(r.Name),
+			r.Task,
+			r.Status(),
+			r.Duration().Round(time.Millisecond),
+			mdEscape(r.Msg)))
+	}
+
+	// Changes planned in plan mode
+	if plans := run.Plan(); len(plans) > 0 {
+		b.WriteString("\n## Planned changes\n\n")
+		b.WriteString("| # | Task | Action | Target | Description |\n")
+		b.WriteString("|---|------|--------|--------|-------------|\n")
+		for _, p := range plans {
+			for _, c := range p.Changes {
+				b.WriteString(fmt.Sprintf("| %s | `%s` | %s | %s | %s |\n",
+					p.Step,
+					p.Task,
+					mdEscape(c.Action),
+					mdEscape(c.Target),
+					mdEscape(c.Description)))
+			}
+		}
+	}
+
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// mdEscape makes a string safe for use in a Markdown table cell. HTML special characters are escaped so
+// that they are shown as text rather than interpreted as markup.
+func mdEscape(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.ReplaceAll(s, "&", "&amp;")
+	s = strings.ReplaceAll(s, "<", "&lt;")
+	s = strings.ReplaceAll(s, ">", "&gt;")
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\r\n", "<br>")
+	s = strings.ReplaceAll(s, "\n", "<br>")
+	return s
+}
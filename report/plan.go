@@ -0,0 +1,31 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package report
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// planReport is the JSON document written by the plan report
+type planReport struct {
+	RunID   string            `json:"run_id"`
+	Name    string            `json:"name,omitempty"`
+	Success bool              `json:"success"`
+	Plan    []shared.TaskPlan `json:"plan"`
+}
+
+// Plan writes the changes planned by the run as a JSON document
+func Plan(out io.Writer, run shared.RunSummary) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(planReport{
+		RunID:   run.RunID,
+		Name:    run.Name,
+		Success: run.Success,
+		Plan:    run.Plan(),
+	})
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// Supported report formats
+const (
+	FormatJUnit    = "junit"
+	FormatMarkdown = "markdown"
+	FormatPlan     = "plan"
+)
+
+// Spec identifies a report format and the file it should be written to
+type Spec struct {
+	Format string
+	Path   string
+}
+
+// ParseSpec parses a report specification in the form format=path, for example junit=results.xml
+func ParseSpec(s string) (Spec, error) {
+	format, path, found := strings.Cut(s, "=")
+	if !found || path == "" {
+		return Spec{}, fmt.Errorf("invalid report specification '%s', expected format=path", s)
+	}
+
+	format = strings.ToLower(strings.TrimSpace(format))
+	switch format {
+	case FormatJUnit, FormatMarkdown, FormatPlan:
+	case "md":
+		format = FormatMarkdown
+	default:
+		return Spec{}, fmt.Errorf("unsupported report format '%s'", format)
+	}
+	return Spec{Format: format, Path: path}, nil
+}
+
+// Render writes a report of the run in the requested format. Secret values are redacted from the results,
+// even if they were not redacted when the results were collected.
+func Render(out io.Writer, format string, run shared.RunSummary) error {
+	results := make([]shared.TaskResult, len(run.Results))
+	for i, r := range run.Results {
+		results[i] = r.Redacted()
+	}
+	run.Results = results
+
+	switch format {
+	case FormatJUnit:
+		return JUnit(out, run)
+	case FormatMarkdown:
+		return Markdown(out, run)
+	case FormatPlan:
+		return Plan(out, run)
+	default:
+		return fmt.Errorf("unsupported report format '%s'", format)
+	}
+}
+
+// Write renders the run to the file identified by the spec
+func Write(spec Spec, run shared.RunSummary) error {
+	file, err := os.Create(spec.Path)
+	if err != nil {
+		return fmt.Errorf("unable to create report file: %w", err)
+	}
+
+	if err = Render(file, spec.Format, run); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// taskLabel returns a human-readable label for a task result
+func taskLabel(r shared.TaskResult) string {
+	if r.Name == "" {
+		return fmt.Sprintf("%s [%s]", r.Step(), r.Task)
+	}
+	return fmt.Sprintf("%s %s [%s]", r.Step(), r.Name, r.Task)
+}
+
+// firstLine returns the first line of a possibly multi-line string
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
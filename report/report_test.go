@@ -0,0 +1,188 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// escapeMsg is a message with characters that must be escaped in XML and Markdown
+const escapeMsg = "a < b & c | d\nsecond line"
+
+// testRun returns a run with one result per outcome. Every task took 1.5 seconds.
+func testRun() shared.RunSummary {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	result := func(sequence int, name string, success bool, msg string) shared.TaskResult {
+		return shared.TaskResult{
+			Sequence:  sequence,
+			Name:      name,
+			Task:      "test_task",
+			Success:   success,
+			Msg:       msg,
+			StartTime: start.Add(time.Duration(sequence) * 2 * time.Second),
+			Timestamp: start.Add(time.Duration(sequence)*2*time.Second + 1500*time.Millisecond),
+		}
+	}
+
+	ok := result(1, "ok", true, "done")
+	changed := result(2, "changed", true, "done")
+	changed.Changed = true
+	failed := result(3, "failed <b> & | c", false, escapeMsg)
+	ignored := result(4, "ignored", false, "tolerated")
+	ignored.Ignored = true
+	skipped := result(5, "skipped", true, "Skipped by operator")
+	skipped.Skipped = true
+	cancelled := result(6, "cancelled", false, "cancelled")
+	cancelled.Cancelled = true
+
+	return shared.RunSummary{
+		RunID:     "run-1",
+		Name:      "deploy",
+		StartTime: start,
+		EndTime:   start.Add(time.Minute),
+		Results:   []shared.TaskResult{ok, changed, failed, ignored, skipped, cancelled},
+	}
+}
+
+// TestJUnit tests that each task becomes a test case with its duration, that failed tasks become failures
+// and skipped and cancelled tasks skipped test cases, and that names and messages are escaped
+func TestJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf, FormatJUnit, testRun()); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "a < b") || !strings.Contains(buf.String(), "a &lt; b &amp; c | d") {
+		t.Errorf("the message is not escaped:\n%s", buf.String())
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("the report is not valid XML: %v\n%s", err, buf.String())
+	}
+	if suites.Tests != 6 || suites.Failures != 1 || suites.Skipped != 2 || suites.Time != "60.000" || len(suites.Suites) != 1 {
+		t.Fatalf("unexpected totals: %+v", suites)
+	}
+
+	tests := []struct {
+		name        string
+		wantFailure string // Failure message, empty if the test case did not fail
+		wantSkipped string // Skipped message, empty if the test case was not skipped
+	}{
+		{"1 ok [test_task]", "", ""},
+		{"2 changed [test_task]", "", ""},
+		{"3 failed <b> & | c [test_task]", "a < b & c | d", ""},
+		{"4 ignored [test_task]", "", ""},
+		{"5 skipped [test_task]", "", "Skipped by operator"},
+		{"6 cancelled [test_task]", "", "cancelled"},
+	}
+	cases := suites.Suites[0].TestCases
+	if len(cases) != len(tests) {
+		t.Fatalf("got %d test cases, want %d", len(cases), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := cases[i]
+			if tc.Name != tt.name {
+				t.Errorf("name %q, want %q", tc.Name, tt.name)
+			}
+			if tc.Time != "1.500" {
+				t.Errorf("time %s, want 1.500", tc.Time)
+			}
+			switch {
+			case tt.wantFailure == "" && tc.Failure != nil:
+				t.Errorf("unexpected failure: %+v", tc.Failure)
+			case tt.wantFailure != "" && (tc.Failure == nil || tc.Failure.Message != tt.wantFailure || tc.Failure.Body != escapeMsg):
+				t.Errorf("expected the failure %q, got %+v", tt.wantFailure, tc.Failure)
+			}
+			switch {
+			case tt.wantSkipped == "" && tc.Skipped != nil:
+				t.Errorf("unexpected skip: %+v", tc.Skipped)
+			case tt.wantSkipped != "" && (tc.Skipped == nil || tc.Skipped.Message != tt.wantSkipped):
+				t.Errorf("expected the skip %q, got %+v", tt.wantSkipped, tc.Skipped)
+			}
+		})
+	}
+}
+
+// TestMarkdown tests that each task becomes a row with its status and duration, and that names and
+// messages are escaped
+func TestMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf, FormatMarkdown, testRun()); err != nil {
+		t.Fatal(err)
+	}
+	report := buf.String()
+	if !strings.Contains(report, "* **Status:** Failed\n") || !strings.Contains(report, "* **Duration:** 1m0s\n") {
+		t.Errorf("unexpected header:\n%s", report)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"ok", "| 1 | ok | `test_task` | ok | 1.5s | done |\n"},
+		{"changed", "| 2 | changed | `test_task` | changed | 1.5s | done |\n"},
+		{"failed", "| 3 | failed &lt;b&gt; &amp; \\| c | `test_task` | failed | 1.5s | a &lt; b &amp; c \\| d<br>second line |\n"},
+		{"ignored", "| 4 | ignored | `test_task` | ignored | 1.5s | tolerated |\n"},
+		{"skipped", "| 5 | skipped | `test_task` | skipped | 1.5s | Skipped by operator |\n"},
+		{"cancelled", "| 6 | cancelled | `test_task` | cancelled | 1.5s | cancelled |\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(report, tt.want) {
+				t.Errorf("missing row %q in:\n%s", tt.want, report)
+			}
+		})
+	}
+}
+
+// TestRenderRedacted tests that secret values are redacted from every report format, including from results
+// that were not redacted when they were collected
+func TestRenderRedacted(t *testing.T) {
+	shared.AddSecret("report-9b3c71")
+	run := testRun()
+	run.Results[2].Msg = "curl -H 'Authorization: Bearer report-9b3c71' failed"
+	run.Results[2].Data = map[string]any{"cmd_args": []any{"--token", "report-9b3c71"}}
+	run.Results[1].Plan = []shared.PlannedChange{{Action: "Run", Target: "report-9b3c71", Description: "use report-9b3c71"}}
+
+	for _, format := range []string{FormatJUnit, FormatMarkdown, FormatPlan} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Render(&buf, format, run); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(buf.String(), "report-9b3c71") || !strings.Contains(buf.String(), shared.Redacted) {
+				t.Errorf("the report is not redacted:\n%s", buf.String())
+			}
+		})
+	}
+}
+
+// TestParseSpec tests that report specifications are parsed and that unknown formats are refused
+func TestParseSpec(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Spec
+		wantErr bool
+	}{
+		{"junit=results.xml", Spec{Format: FormatJUnit, Path: "results.xml"}, false},
+		{"MD=summary.md", Spec{Format: FormatMarkdown, Path: "summary.md"}, false},
+		{"plan=out/plan.json", Spec{Format: FormatPlan, Path: "out/plan.json"}, false},
+		{"junit", Spec{}, true},
+		{"junit=", Spec{}, true},
+		{"html=report.html", Spec{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSpec(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: got %+v, %v", tt.in, got, err)
+		}
+	}
+}
@@ -6,18 +6,18 @@ package cloudaws
 import (
 	"context"
 	"encoding/json"
-	"fmt"
-	"os"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/joho/godotenv"
+
+	"github.com/OpsBlade/OpsBlade/shared"
 )
 
 type CloudAWS struct {
-	Config *AWSConfig
-	AWS    *aws.Config
+	Config   *AWSConfig
+	AWS      *aws.Config
+	Identity *shared.AWSIdentity // Caller identity, resolved only if the task has an AWS guard or is audited
 }
 
 type AWSConfig struct {
@@ -26,6 +26,7 @@ type AWSConfig struct {
 	AccessKey   string
 	SecretKey   string
 	Environment string
+	task        *shared.TaskContext
 }
 
 type Option func(*AWSConfig)
@@ -39,50 +40,67 @@ func New(options ...Option) (*CloudAWS, error) {
 	}
 
 	var awsCfg aws.Config
-	var err error
 
-	// If an environment file is provided, load it. If not, load from the default AWS files
-	if cfg.Environment != "" {
-		err = godotenv.Load(cfg.Environment)
-		if err != nil {
-			return nil, err
+	// The task's defaults apply if the task does not set a profile or region
+	if cfg.task != nil {
+		if cfg.Profile == "" {
+			cfg.Profile = cfg.task.AWSProfile
+		}
+		if cfg.Region == "" {
+			cfg.Region = cfg.task.AWSRegion
 		}
 	}
 
-	// Attempt to load from the environment
-	cfg.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
-	cfg.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
-	cfg.Region = os.Getenv("AWS_REGION")
+	// Read the environment file, if one is provided, without changing the process environment
+	env, err := shared.LoadEnv(cfg.Environment)
+	if err != nil {
+		return nil, err
+	}
+
+	// If the environment file sets credentials, only its credentials are used, so that credentials in the
+	// process environment are never used with the settings of the file
+	credential := env.Group("AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_PROFILE")
+	cfg.AccessKey = credential("AWS_ACCESS_KEY_ID")
+	cfg.SecretKey = credential("AWS_SECRET_ACCESS_KEY")
+	sessionToken := credential("AWS_SESSION_TOKEN")
+	if cfg.Profile == "" {
+		cfg.Profile = credential("AWS_PROFILE")
+	}
+	if cfg.Region == "" {
+		cfg.Region = env.Get("AWS_REGION")
+	}
+
+	var loadOptions []func(*config.LoadOptions) error
+	if endpoint, ok := env.Lookup("AWS_ENDPOINT_URL"); ok && endpoint != "" {
+		loadOptions = append(loadOptions, config.WithBaseEndpoint(endpoint))
+	}
+	if cfg.Region != "" { // If region is set, use it
+		loadOptions = append(loadOptions, config.WithRegion(cfg.Region))
+	}
 
 	if cfg.AccessKey != "" && cfg.SecretKey != "" {
-		awsCfg, err = config.LoadDefaultConfig(context.TODO(),
-			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(cfg.AccessKey, cfg.SecretKey, "")),
-			config.WithRegion(cfg.Region))
+		loadOptions = append(loadOptions,
+			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(cfg.AccessKey, cfg.SecretKey, sessionToken)))
 	} else if cfg.Profile != "" { // If profile is provided, use shared config profile
-		if cfg.Region == "" { // If region is set, use it
-			awsCfg, err = config.LoadDefaultConfig(context.TODO(),
-				config.WithSharedConfigProfile(cfg.Profile))
-		} else {
-			awsCfg, err = config.LoadDefaultConfig(context.TODO(),
-				config.WithSharedConfigProfile(cfg.Profile),
-				config.WithRegion(cfg.Region))
-		}
-	} else { // Otherwise, use the default credential provider chain
-		if cfg.Region == "" { // If region is set, use it
-			awsCfg, err = config.LoadDefaultConfig(context.TODO())
-		} else {
-			awsCfg, err = config.LoadDefaultConfig(context.TODO(),
-				config.WithRegion(cfg.Region))
-		}
-	}
+		loadOptions = append(loadOptions, config.WithSharedConfigProfile(cfg.Profile))
+	} // Otherwise, use the default credential provider chain
+	awsCfg, err = config.LoadDefaultConfig(context.TODO(), loadOptions...)
 
 	// Return an error if unable to configure
 	if err != nil {
 		return nil, err
 	}
 
+	// Enforce the workflow's AWS guard before the client is used, and identify the caller of audited tasks
+	c := &CloudAWS{AWS: &awsCfg, Config: cfg}
+	if cfg.task != nil && (cfg.task.AWSGuard != nil || cfg.task.Audit) {
+		if err = c.guard(cfg.task); err != nil {
+			return nil, err
+		}
+	}
+
 	// Return the CloudAWS struct with the loaded configuration
-	return &CloudAWS{AWS: &awsCfg, Config: cfg}, nil
+	return c, nil
 }
 
 func WithProfile(profile string) Option {
@@ -109,12 +127,20 @@ func WithEnvironment(env string) Option {
 	}
 }
 
+// WithTaskContext enforces the task's AWS guard, if it has one, and records the resolved caller identity in
+// the task context so that it is included in the task result and the audit log
+func WithTaskContext(task *shared.TaskContext) Option {
+	return func(cfg *AWSConfig) {
+		cfg.task = task
+	}
+}
+
 func (c *CloudAWS) Dump() {
 	// Marshall the configuration to JSON and print it
 	data, err := json.MarshalIndent(c.Config, "", "  ")
 	if err != nil {
-		fmt.Printf("Failed to marshal CloudAWS config: %s\n", err.Error())
+		shared.Printf("Failed to marshal CloudAWS config: %s\n", err.Error())
 		return
 	}
-	fmt.Printf("cloudaws:\n%s\n\n", string(data))
+	shared.Printf("cloudaws:\n%s\n\n", string(data))
 }
@@ -4,6 +4,9 @@
 package cloudaws
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
@@ -23,3 +26,54 @@ func FiltersToEC2(filters []shared.Filter) []types.Filter {
 	}
 	return awsFilters
 }
+
+// Instance returns the description of an instance
+func (c *CloudAWS) Instance(instanceID string) (*types.Instance, error) {
+	resp, err := c.EC2Client().DescribeInstances(context.TODO(), &ec2.DescribeInstancesInput{
+		InstanceIds: []string{instanceID},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, reservation := range resp.Reservations {
+		for _, instance := range reservation.Instances {
+			return &instance, nil
+		}
+	}
+	return nil, fmt.Errorf("instance %s not found", instanceID)
+}
+
+// InstanceState returns the current state name (pending, running, stopping, stopped, etc.) of an instance
+func (c *CloudAWS) InstanceState(instanceID string) (string, error) {
+	instance, err := c.Instance(instanceID)
+	if err != nil {
+		return "", err
+	}
+	if instance.State == nil {
+		return "", fmt.Errorf("instance %s has no state", instanceID)
+	}
+	return string(instance.State.Name), nil
+}
+
+// DescribeInstance returns the ID, state, type, and tags of an instance as a map, for use by policy rules
+func (c *CloudAWS) DescribeInstance(instanceID string) (map[string]any, error) {
+	instance, err := c.Instance(instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]any, len(instance.Tags))
+	for _, tag := range instance.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	description := map[string]any{
+		"instance_id":   instanceID,
+		"instance_type": string(instance.InstanceType),
+		"tags":          tags,
+	}
+	if instance.State != nil {
+		description["state"] = string(instance.State.Name)
+	}
+	return description, nil
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package cloudaws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// guard resolves the caller identity, records it in the task context, and checks it against the task's
+// AWS guard, if it has one. It is called by New before the client is returned to the task, if the task has
+// an AWS guard or is audited.
+func (c *CloudAWS) guard(task *shared.TaskContext) error {
+	resp, err := sts.NewFromConfig(*c.AWS).GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return fmt.Errorf("unable to resolve the AWS caller identity: %w", err)
+	}
+
+	id := shared.AWSIdentity{
+		Account: aws.ToString(resp.Account),
+		ARN:     aws.ToString(resp.Arn),
+		Region:  c.AWS.Region,
+	}
+	c.Identity = &id
+	task.AWSIdentity = &id
+
+	if task.Debug {
+		shared.Printf("cloudaws: AWS %s\n\n", id.String())
+	}
+	if task.AWSGuard == nil {
+		return nil
+	}
+	return task.AWSGuard.Check(id)
+}
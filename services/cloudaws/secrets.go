@@ -0,0 +1,35 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package cloudaws
+
+import (
+	"fmt"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// Secret providers for AWS SSM Parameter Store and AWS Secrets Manager, e.g. {{secret:ssm:/prod/slack/token}}
+// or {{secret:secretsmanager:prod/jira}}. They use the default AWS credentials and region.
+const (
+	SecretProviderSSM            = "ssm"
+	SecretProviderSecretsManager = "secretsmanager"
+)
+
+func init() {
+	shared.RegisterSecretProvider(SecretProviderSSM, awsSecrets{resolve: (*CloudAWS).Parameter})
+	shared.RegisterSecretProvider(SecretProviderSecretsManager, awsSecrets{resolve: (*CloudAWS).SecretString})
+}
+
+// awsSecrets resolves secrets with an AWS client
+type awsSecrets struct {
+	resolve func(c *CloudAWS, name string) (string, error)
+}
+
+func (p awsSecrets) Secret(name string) (string, error) {
+	c, err := New()
+	if err != nil {
+		return "", fmt.Errorf("failed to create AWS client: %w", err)
+	}
+	return p.resolve(c, name)
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package cloudaws
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// TestSecretProviders tests that the AWS secret providers send signed requests for the secret and return its
+// value, and that a binary secret is refused
+func TestSecretProviders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDTEST/") {
+			http.Error(w, `{"__type":"UnrecognizedClientException"}`, http.StatusForbidden)
+			return
+		}
+		var in map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		switch target := r.Header.Get("X-Amz-Target"); {
+		case target == "AmazonSSM.GetParameter" && in["Name"] == "/prod/token" && in["WithDecryption"] == true:
+			_, _ = w.Write([]byte(`{"Parameter":{"Name":"/prod/token","Type":"SecureString","Value":"ssm-value"}}`))
+		case target == "secretsmanager.GetSecretValue" && in["SecretId"] == "prod/jira":
+			_, _ = w.Write([]byte(`{"Name":"prod/jira","SecretString":"sm-value"}`))
+		case target == "secretsmanager.GetSecretValue" && in["SecretId"] == "prod/cert":
+			_, _ = w.Write([]byte(`{"Name":"prod/cert","SecretBinary":"AAEC"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_ENDPOINT_URL", server.URL)
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDTEST")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_PROFILE", "")
+
+	tests := []struct {
+		provider awsSecrets
+		name     string
+		want     string
+		wantErr  string
+	}{
+		{awsSecrets{resolve: (*CloudAWS).Parameter}, "/prod/token", "ssm-value", ""},
+		{awsSecrets{resolve: (*CloudAWS).Parameter}, "/prod/missing", "", "ResourceNotFoundException"},
+		{awsSecrets{resolve: (*CloudAWS).SecretString}, "prod/jira", "sm-value", ""},
+		{awsSecrets{resolve: (*CloudAWS).SecretString}, "prod/cert", "", "binary"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.provider.Secret(tt.name)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// References to the registered providers resolve through them
+	if got, err := shared.ResolveSecret("ssm:/prod/token", ""); err != nil || got != "ssm-value" {
+		t.Errorf("got %q, %v", got, err)
+	}
+}
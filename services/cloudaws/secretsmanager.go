@@ -0,0 +1,31 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package cloudaws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// SecretsManagerClient returns a Secrets Manager client
+func (c *CloudAWS) SecretsManagerClient() *secretsmanager.Client {
+	return secretsmanager.NewFromConfig(*c.AWS)
+}
+
+// SecretString returns the string value of a Secrets Manager secret
+func (c *CloudAWS) SecretString(secretID string) (string, error) {
+	resp, err := c.SecretsManagerClient().GetSecretValue(context.TODO(), &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretID),
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.SecretString == nil {
+		return "", fmt.Errorf("secret %s is binary, only string secrets are supported", secretID)
+	}
+	return *resp.SecretString, nil
+}
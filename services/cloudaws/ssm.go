@@ -0,0 +1,32 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package cloudaws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// SSMClient returns an SSM client
+func (c *CloudAWS) SSMClient() *ssm.Client {
+	return ssm.NewFromConfig(*c.AWS)
+}
+
+// Parameter returns the value of an SSM Parameter Store parameter, decrypted if it is a SecureString
+func (c *CloudAWS) Parameter(name string) (string, error) {
+	resp, err := c.SSMClient().GetParameter(context.TODO(), &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.Parameter == nil || resp.Parameter.Value == nil {
+		return "", fmt.Errorf("parameter %s has no value", name)
+	}
+	return *resp.Parameter.Value, nil
+}
@@ -5,9 +5,8 @@ package cloudjira
 
 import (
 	"fmt"
-	"os"
 
-	"github.com/joho/godotenv"
+	"github.com/OpsBlade/OpsBlade/shared"
 )
 
 type CloudJira struct {
@@ -19,6 +18,7 @@ type JiraConfig struct {
 	Username    string
 	Token       string
 	BaseURL     string
+	task        *shared.TaskContext
 }
 
 type Option func(*JiraConfig)
@@ -31,24 +31,29 @@ func New(options ...Option) (*CloudJira, error) {
 		opt(cfg)
 	}
 
-	// If an environment file is provided, load it
-	if cfg.Environment != "" {
-		err := godotenv.Load(cfg.Environment)
-		if err != nil {
-			return nil, err
-		}
+	// Read the environment file, if one is provided, without changing the process environment
+	env, err := shared.LoadEnv(cfg.Environment)
+	if err != nil {
+		return nil, err
 	}
 
-	// Load from environment variables
-	cfg.Username = os.Getenv("JIRA_USER")
-	cfg.Token = os.Getenv("JIRA_TOKEN")
-	cfg.BaseURL = os.Getenv("JIRA_URL")
+	// Load from the environment file, or from environment variables if the file does not configure Jira, so
+	// that the URL and the credentials always come from the same place
+	setting := env.Group("JIRA_USER", "JIRA_TOKEN", "JIRA_URL")
+	cfg.Username = setting("JIRA_USER")
+	cfg.Token = setting("JIRA_TOKEN")
+	cfg.BaseURL = setting("JIRA_URL")
 
 	// Validate that required fields are set
 	if cfg.Username == "" || cfg.Token == "" || cfg.BaseURL == "" {
 		return nil, fmt.Errorf("missing required Jira configuration")
 	}
 
+	// Record the Jira user in the task context, so that it is included in the task result and the audit log
+	if cfg.task != nil {
+		cfg.task.JiraUser = cfg.Username
+	}
+
 	// Return the Jira struct with the loaded configuration
 	return &CloudJira{
 		Config: *cfg,
@@ -62,3 +67,10 @@ func WithEnvironment(env string) Option {
 		}
 	}
 }
+
+// WithTaskContext records the Jira user in the task context
+func WithTaskContext(task *shared.TaskContext) Option {
+	return func(cfg *JiraConfig) {
+		cfg.task = task
+	}
+}
@@ -5,9 +5,8 @@ package cloudslack
 
 import (
 	"fmt"
-	"os"
 
-	"github.com/joho/godotenv"
+	"github.com/OpsBlade/OpsBlade/shared"
 )
 
 type CloudSlack struct {
@@ -32,18 +31,15 @@ func New(options ...Option) (*CloudSlack, error) {
 		opt(cfg)
 	}
 
-	if cfg.Env != "" {
-		err := godotenv.Load(cfg.Env)
-		if err != nil {
-			return nil, err
-		}
+	// Read the environment file, if one is provided, without changing the process environment
+	env, err := shared.LoadEnv(cfg.Env)
+	if err != nil {
+		return nil, err
 	}
 
-	if cfg.EnvSuffix != "" {
-		cfg.Webhook = os.Getenv("SLACK_WEBHOOK" + cfg.EnvSuffix)
-	} else {
-		cfg.Webhook = os.Getenv("SLACK_WEBHOOK")
-	}
+	// If the environment file configures a webhook, the webhook is only read from the file
+	key := "SLACK_WEBHOOK" + cfg.EnvSuffix
+	cfg.Webhook = env.Group("SLACK_WEBHOOK", key)(key)
 
 	// Validate that required fields are set
 	if cfg.Webhook == "" {
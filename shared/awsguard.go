@@ -0,0 +1,101 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package shared
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// AWSIdentity is the AWS account, caller, and region a task uses
+type AWSIdentity struct {
+	Account string `json:"account"`
+	ARN     string `json:"arn"`
+	Region  string `json:"region"`
+}
+
+// String returns a human-readable description of the identity
+func (id *AWSIdentity) String() string {
+	return fmt.Sprintf("account %s, region %s, %s", id.Account, id.Region, id.ARN)
+}
+
+// AWSGuard restricts the AWS accounts and regions that the tasks of a workflow may use. Empty lists allow
+// any account or region. Accounts listed in Confirm must be confirmed by the operator, once per run, before
+// they are used. A child workflow's guard is checked after the guard of its parent.
+type AWSGuard struct {
+	Accounts  []string        `yaml:"accounts" json:"accounts,omitempty"` // Allowed account IDs
+	Regions   []string        `yaml:"regions" json:"regions,omitempty"`   // Allowed regions
+	Confirm   []string        `yaml:"confirm" json:"confirm,omitempty"`   // Account IDs that require confirmation
+	parent    *AWSGuard       `yaml:"-"`
+	mu        sync.Mutex      `yaml:"-"`
+	confirmed map[string]bool `yaml:"-"`
+}
+
+// SetParent sets the guard of the parent workflow, which is checked first
+func (g *AWSGuard) SetParent(parent *AWSGuard) {
+	g.parent = parent
+}
+
+// Preconfirm records accounts confirmed in advance, e.g. on the command line, so that the operator is not
+// asked to confirm them
+func (g *AWSGuard) Preconfirm(accounts []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.confirmed == nil {
+		g.confirmed = make(map[string]bool)
+	}
+	for _, account := range accounts {
+		g.confirmed[account] = true
+	}
+}
+
+// Check returns an error if the identity may not be used. If the account requires confirmation and was not
+// confirmed yet, the operator is asked to type the account ID. Without a terminal the account must be
+// confirmed in advance.
+func (g *AWSGuard) Check(id AWSIdentity) error {
+	if g.parent != nil {
+		if err := g.parent.Check(id); err != nil {
+			return err
+		}
+	}
+
+	if len(g.Accounts) > 0 && !slices.Contains(g.Accounts, id.Account) {
+		return fmt.Errorf("AWS account %s (%s) is not allowed by aws_guard", id.Account, id.ARN)
+	}
+	if len(g.Regions) > 0 && !slices.Contains(g.Regions, id.Region) {
+		if id.Region == "" {
+			return fmt.Errorf("no AWS region is set, and aws_guard only allows %v", g.Regions)
+		}
+		return fmt.Errorf("AWS region %s is not allowed by aws_guard", id.Region)
+	}
+	if !slices.Contains(g.Confirm, id.Account) {
+		return nil
+	}
+
+	// Confirmation is asked once per account, even if tasks run in the background
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.confirmed[id.Account] {
+		return nil
+	}
+	if !IsTerminal() {
+		return fmt.Errorf("AWS account %s requires confirmation, use --confirm-account %s", id.Account, id.Account)
+	}
+
+	Printf("* This workflow is about to use AWS %s\n", id.String())
+	answer, err := Prompt("Type the account ID to continue: ", 0, nil)
+	Println()
+	if err != nil {
+		return fmt.Errorf("AWS account %s was not confirmed: %w", id.Account, err)
+	}
+	if answer != id.Account {
+		return fmt.Errorf("AWS account %s was not confirmed", id.Account)
+	}
+	if g.confirmed == nil {
+		g.confirmed = make(map[string]bool)
+	}
+	g.confirmed[id.Account] = true
+	return nil
+}
@@ -3,8 +3,15 @@
 
 package shared
 
-// Callback defines an interface that calling processes can optionally use to receive task start and result information
+// Callback defines an interface that calling processes can optionally use to receive task start and result information.
+// OnStop returns false to stop the workflow. The workflow also stops after a failed task whose failure is not
+// ignored, whatever OnStop returns.
 type Callback interface {
 	OnStart(info TaskInfo) bool
 	OnStop(result TaskResult) bool
 }
+
+// EventCallback may optionally be implemented by a Callback to also receive workflow-level events
+type EventCallback interface {
+	OnEvent(event WorkflowEvent)
+}
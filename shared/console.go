@@ -0,0 +1,33 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package shared
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Console is the destination for human-readable output from tasks (progress messages, debug dumps, etc.).
+// If nil, os.Stdout is used. Machine-readable output modes redirect this to os.Stderr so that stdout
+// only contains structured events.
+var Console io.Writer
+
+// console returns the current console writer
+func console() io.Writer {
+	if Console == nil {
+		return os.Stdout
+	}
+	return Console
+}
+
+// Printf writes formatted human-readable output to the console, with secret values redacted
+func Printf(format string, a ...any) {
+	_, _ = fmt.Fprint(console(), Redact(fmt.Sprintf(format, a...)))
+}
+
+// Println writes human-readable output to the console followed by a newline, with secret values redacted
+func Println(a ...any) {
+	_, _ = fmt.Fprint(console(), Redact(fmt.Sprintln(a...)))
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package shared
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Encrypted files start with a versioned header: the magic string, a version byte, the salt used to
+// derive the key from the passphrase, and the nonce. The rest of the file is the AES-256-GCM ciphertext,
+// authenticated together with the header.
+const (
+	encryptedMagic      = "OPSBLADE-ENC"
+	encryptedVersion    = 1
+	encryptedSaltSize   = 16
+	encryptedIterations = 600000 // PBKDF2-HMAC-SHA256 iterations for version 1
+)
+
+// Environment variables that provide the passphrase for encrypted files
+const (
+	PassphraseEnv = "OPSBLADE_PASSPHRASE" // Passphrase
+	KeyFileEnv    = "OPSBLADE_KEY_FILE"   // File containing the passphrase
+)
+
+var ErrNotEncrypted = errors.New("not an encrypted OpsBlade file")
+
+// Passphrase returns the passphrase for encrypted files. It is read from the key file if one is provided,
+// otherwise from the environment variable passphraseEnv if provided, otherwise from the file named by
+// OPSBLADE_KEY_FILE or the OPSBLADE_PASSPHRASE environment variable. Environment variables are read from
+// the task's env file, falling back to the process environment, or only from the process environment if
+// env is nil. Surrounding whitespace is ignored.
+//
+//goland:noinspection GoUnusedExportedFunction
+func Passphrase(passphraseEnv, keyFile string, env *Env) (string, error) {
+	if env == nil {
+		env = &Env{}
+	}
+	if keyFile == "" && passphraseEnv == "" {
+		keyFile = env.Get(KeyFileEnv)
+		passphraseEnv = PassphraseEnv
+	}
+
+	var passphrase string
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return "", fmt.Errorf("unable to read key file: %w", err)
+		}
+		if passphrase = strings.TrimSpace(string(data)); passphrase == "" {
+			return "", fmt.Errorf("key file %s is empty", keyFile)
+		}
+	} else {
+		passphrase = strings.TrimSpace(env.Get(passphraseEnv))
+	}
+
+	if passphrase == "" && passphraseEnv == PassphraseEnv {
+		return "", fmt.Errorf("no passphrase, set %s or %s", PassphraseEnv, KeyFileEnv)
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("no passphrase, environment variable %s is not set", passphraseEnv)
+	}
+	AddSecret(passphrase)
+	return passphrase, nil
+}
+
+// IsEncrypted returns true if data starts with the header of an encrypted file
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encryptedMagic))
+}
+
+// Encrypt encrypts data with a key derived from the passphrase
+//
+//goland:noinspection GoUnusedExportedFunction
+func Encrypt(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, encryptedSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	header := append([]byte(encryptedMagic), encryptedVersion)
+	header = append(header, salt...)
+	header = append(header, nonce...)
+	return gcm.Seal(header, nonce, plaintext, header), nil
+}
+
+// Decrypt decrypts data encrypted by Encrypt. It fails if the passphrase is wrong or the data was modified.
+//
+//goland:noinspection GoUnusedExportedFunction
+func Decrypt(data []byte, passphrase string) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, ErrNotEncrypted
+	}
+	rest := data[len(encryptedMagic):]
+	if len(rest) < 1 || rest[0] != encryptedVersion {
+		return nil, fmt.Errorf("unsupported encrypted file version")
+	}
+	rest = rest[1:]
+	if len(rest) < encryptedSaltSize {
+		return nil, fmt.Errorf("encrypted file is truncated")
+	}
+
+	gcm, err := newGCM(passphrase, rest[:encryptedSaltSize])
+	if err != nil {
+		return nil, err
+	}
+	rest = rest[encryptedSaltSize:]
+	if len(rest) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, fmt.Errorf("encrypted file is truncated")
+	}
+
+	headerLen := len(data) - len(rest) + gcm.NonceSize()
+	plaintext, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], data[:headerLen])
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt: wrong passphrase or modified file")
+	}
+	return plaintext, nil
+}
+
+// ReadEncryptedFile reads and decrypts a file
+//
+//goland:noinspection GoUnusedExportedFunction
+func ReadEncryptedFile(filename, passphrase string) ([]byte, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := Decrypt(data, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return plaintext, nil
+}
+
+// newGCM returns an AES-256-GCM cipher with a key derived from the passphrase and salt
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, encryptedIterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package shared
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestEncryptDecrypt tests that encrypted data decrypts with the passphrase, and that a wrong passphrase or
+// any modification of the header or the ciphertext is detected
+func TestEncryptDecrypt(t *testing.T) {
+	plaintext := []byte("AWS_SECRET_ACCESS_KEY=very-secret\n")
+	encrypted, err := Encrypt(plaintext, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(encrypted) || bytes.Contains(encrypted, []byte("very-secret")) {
+		t.Fatal("the data is not encrypted")
+	}
+
+	// Offsets of the parts of the header
+	version := len(encryptedMagic)
+	salt := version + 1
+	nonce := salt + encryptedSaltSize
+
+	// tamper returns a copy of the encrypted data with the byte at i inverted
+	tamper := func(i int) []byte {
+		data := bytes.Clone(encrypted)
+		data[i] ^= 0xff
+		return data
+	}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		passphrase string
+		wantErr    bool
+	}{
+		{"round trip", encrypted, "correct horse", false},
+		{"wrong passphrase", encrypted, "battery staple", true},
+		{"tampered ciphertext", tamper(len(encrypted) - 1), "correct horse", true},
+		{"tampered first ciphertext byte", tamper(nonce + 12), "correct horse", true},
+		{"tampered magic", tamper(0), "correct horse", true},
+		{"tampered version", tamper(version), "correct horse", true},
+		{"tampered salt", tamper(salt), "correct horse", true},
+		{"tampered nonce", tamper(nonce), "correct horse", true},
+		{"truncated", encrypted[:nonce+4], "correct horse", true},
+		{"not encrypted", plaintext, "correct horse", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decrypt(tt.data, tt.passphrase)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, decrypted %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("got %q, want %q", got, plaintext)
+			}
+		})
+	}
+
+	if _, err = Decrypt(plaintext, "correct horse"); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("expected ErrNotEncrypted, got %v", err)
+	}
+
+	// The salt and nonce are random, so encrypting the same data twice gives different results
+	again, err := Encrypt(plaintext, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(again, encrypted) {
+		t.Error("encrypting twice gave the same result")
+	}
+}
+
+// TestPassphrase tests that the passphrase is read from the task's env file, falling back to the process
+// environment, and from a key file
+func TestPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "passphrase.key")
+	if err := os.WriteFile(keyFile, []byte("  key file passphrase\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	envFile := filepath.Join(dir, "prod.env")
+	if err := os.WriteFile(envFile, []byte("OPSBLADE_PASSPHRASE=env file passphrase\nVARS_PASSPHRASE=env file vars\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keyEnvFile := filepath.Join(dir, "key.env")
+	if err := os.WriteFile(keyEnvFile, []byte("OPSBLADE_KEY_FILE="+keyFile+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(PassphraseEnv, "process passphrase")
+	t.Setenv(KeyFileEnv, "")
+	t.Setenv("PROCESS_PASSPHRASE", "process vars")
+
+	tests := []struct {
+		name          string
+		passphraseEnv string
+		keyFile       string
+		envFile       string
+		want          string
+		wantErr       bool
+	}{
+		{"default from the env file", "", "", envFile, "env file passphrase", false},
+		{"default from the process environment", "", "", "", "process passphrase", false},
+		{"key file named by the env file", "", "", keyEnvFile, "key file passphrase", false},
+		{"passphrase_env from the env file", "VARS_PASSPHRASE", "", envFile, "env file vars", false},
+		{"passphrase_env from the process environment", "PROCESS_PASSPHRASE", "", envFile, "process vars", false},
+		{"key_file", "VARS_PASSPHRASE", keyFile, envFile, "key file passphrase", false},
+		{"passphrase_env not set", "MISSING_PASSPHRASE", "", envFile, "", true},
+		{"missing key file", "", filepath.Join(dir, "missing.key"), "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var env *Env
+			if tt.envFile != "" {
+				var err error
+				if env, err = LoadEnv(tt.envFile); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := Passphrase(tt.passphraseEnv, tt.keyFile, env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package shared
+
+// Describer is implemented by tasks that can describe the resource they act on, such as the tags of an
+// instance, so that policy rules can refer to it. Describe must not change anything.
+type Describer interface {
+	Describe() (map[string]any, error)
+}
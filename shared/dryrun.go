@@ -8,6 +8,24 @@ import (
 	"strings"
 )
 
+// Every task must honor TaskContext.DryRun:
+//
+//   - A dry run has no side effects. A task must not make mutating requests to external services,
+//     write or delete files, or run commands. Read-only requests are permitted so that later tasks
+//     receive real data, and AWS requests that would change something must pass the DryRun flag.
+//   - A task that would change something returns DryRunResult, which succeeds, is reported as changed,
+//     and describes the action the task would have taken.
+//   - The data a dry run returns has the same keys as the data of a real run. Values that are only
+//     known once the change is made are returned as Placeholder values.
+
+// DryRunResult returns the result of a task that would have changed something if it were not a dry
+// run. The action describes what the task would have done, e.g. "delete file /tmp/marker".
+func (c *TaskContext) DryRunResult(action string, data any) TaskResult {
+	r := c.Result(true, fmt.Sprintf("Dry run: would %s", action), data)
+	r.Changed = true
+	return r
+}
+
 func DryRunErrCheck(err error) bool {
 	e := strings.ToLower(fmt.Sprintf("%s", err.Error()))
 	if strings.Contains(e, "api error dryrunoperation: request would have succeeded") {
@@ -16,7 +16,7 @@ func DumpTask(s any) {
 
 	// Encapsulate to be JSON parser friendly
 	data := map[string]any{"message_type": "task_dump", "task_dump": taskCopy}
-	fmt.Println(Dump(data) + "\n")
+	Println(Dump(data) + "\n")
 }
 
 // copyTaskWithoutInstructions creates a deep copy of a task struct but with Context.Instructions set to nil
@@ -63,16 +63,16 @@ func TestDumpTask(t *testing.T) {
 		t.Errorf("Failed to parse JSON output: %v", err)
 	}
 
-	// Check that the dump field exists
-	dump, ok := result["dump"]
+	// Check that the task_dump field exists
+	dump, ok := result["task_dump"]
 	if !ok {
-		t.Errorf("Output JSON does not contain 'dump' field: %s", jsonStr)
+		t.Errorf("Output JSON does not contain 'task_dump' field: %s", jsonStr)
 	}
 
 	// Check that the task_dump field contains a task with Context
 	taskDump, ok := dump.(map[string]interface{})
 	if !ok {
-		t.Errorf("'dump' field is not a map: %v", dump)
+		t.Errorf("'task_dump' field is not a map: %v", dump)
 	}
 
 	// Check that Context exists in the task_dump
@@ -3,9 +3,101 @@
 
 package shared
 
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
 func SelectEnv(taskEnv, globalEnv string) string {
 	if taskEnv != "" {
 		return taskEnv
 	}
 	return globalEnv
 }
+
+// Env contains the variables of an env file, falling back to the process environment for variables that
+// the file does not set. Env files are never loaded into the process environment, so that the credentials
+// of one task are not used by a later task with a different env file.
+type Env struct {
+	File string
+	vars map[string]string
+}
+
+// LoadEnv reads an env file. An empty filename returns the process environment.
+//
+//goland:noinspection GoUnusedExportedFunction
+func LoadEnv(filename string) (*Env, error) {
+	e := &Env{File: filename, vars: make(map[string]string)}
+	if filename == "" {
+		return e, nil
+	}
+	vars, err := godotenv.Read(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read env file %s: %w", filename, err)
+	}
+	e.vars = vars
+	return e, nil
+}
+
+// Get returns a variable of the env file, or of the process environment if the file does not set it
+func (e *Env) Get(key string) string {
+	if value, ok := e.vars[key]; ok {
+		return value
+	}
+	return os.Getenv(key)
+}
+
+// Lookup returns a variable of the env file, ignoring the process environment
+func (e *Env) Lookup(key string) (string, bool) {
+	value, ok := e.vars[key]
+	return value, ok
+}
+
+// Sets returns true if the env file sets any of the variables
+func (e *Env) Sets(keys ...string) bool {
+	for _, key := range keys {
+		if _, ok := e.vars[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Group returns a function that reads the variables of one service, such as its URL and credentials. If the
+// env file sets any of the keys, they are only read from the file, so that the settings of a service never
+// come partly from the file and partly from the process environment. Otherwise they are read from the
+// process environment.
+func (e *Env) Group(keys ...string) func(key string) string {
+	if !e.Sets(keys...) {
+		return e.Get
+	}
+	return func(key string) string {
+		value, _ := e.Lookup(key)
+		return value
+	}
+}
+
+// Environ returns the process environment with the variables of the env file added or replaced, in the
+// form used by os/exec for the environment of a subprocess
+func (e *Env) Environ() []string {
+	environ := make([]string, 0, len(os.Environ())+len(e.vars))
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if _, ok := e.vars[key]; !ok {
+			environ = append(environ, kv)
+		}
+	}
+	keys := make([]string, 0, len(e.vars))
+	for key := range e.vars {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		environ = append(environ, key+"="+e.vars[key])
+	}
+	return environ
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestEnvGroup tests that the variables of a group are only read from the env file if it sets any of them,
+// and otherwise from the process environment
+func TestEnvGroup(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "jira.env")
+	if err := os.WriteFile(filename, []byte("ENV_GROUP_URL=https://file.example.com\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ENV_GROUP_URL", "https://process.example.com")
+	t.Setenv("ENV_GROUP_TOKEN", "process-token")
+	t.Setenv("ENV_GROUP_OTHER", "process-other")
+
+	env, err := LoadEnv(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		group []string
+		key   string
+		want  string
+	}{
+		{"set by the file", []string{"ENV_GROUP_URL", "ENV_GROUP_TOKEN"}, "ENV_GROUP_URL", "https://file.example.com"},
+		{"not mixed with the process environment", []string{"ENV_GROUP_URL", "ENV_GROUP_TOKEN"}, "ENV_GROUP_TOKEN", ""},
+		{"group not set by the file", []string{"ENV_GROUP_OTHER"}, "ENV_GROUP_OTHER", "process-other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := env.Group(tt.group...)(tt.key); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Without an env file, the process environment is used
+	env, err = LoadEnv("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := env.Group("ENV_GROUP_URL", "ENV_GROUP_TOKEN")("ENV_GROUP_TOKEN"); got != "process-token" {
+		t.Errorf("got %q, want the process environment", got)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package shared
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Planner is implemented by tasks that change something. In plan mode the workflow calls Plan instead
+// of Execute. Plan must not call mutating APIs, but may read the current state (e.g. describe a launch
+// template) to describe precisely what Execute would do.
+type Planner interface {
+	Plan() TaskResult
+}
+
+// PlannedChange describes a single change that a task would make
+type PlannedChange struct {
+	Action      string         `json:"action"`            // API call or operation, e.g. CreateLaunchTemplateVersion
+	Target      string         `json:"target,omitempty"`  // Resource that would be changed
+	Description string         `json:"description"`       // Human-readable description of the change
+	Details     map[string]any `json:"details,omitempty"` // Parameters of the change
+}
+
+// TaskPlan is the list of changes planned by a single task
+type TaskPlan struct {
+	Step    string          `json:"step"`
+	Name    string          `json:"name,omitempty"`
+	Task    string          `json:"task"`
+	Changes []PlannedChange `json:"changes"`
+}
+
+// Planned returns the result of a task's Plan method. The task is reported as changed if it plans
+// any changes. Data may hold the values the task already knows, so that later tasks can use them.
+func (c *TaskContext) Planned(changes []PlannedChange, data any) TaskResult {
+	msg := "No changes planned"
+	if len(changes) > 0 {
+		msg = fmt.Sprintf("%d change(s) planned", len(changes))
+	}
+	r := c.Result(true, msg, data)
+	r.Plan = changes
+	r.Changed = len(changes) > 0
+	return r
+}
+
+// Placeholder returns the value a planned task or a dry run returns for data that is only known once
+// the change is made, such as the ID of a new AMI
+func Placeholder(name string) string {
+	return fmt.Sprintf("(pending %s)", name)
+}
+
+// String returns a human-readable description of the change
+func (pc *PlannedChange) String() string {
+	if pc.Target == "" {
+		return fmt.Sprintf("%s: %s", pc.Action, pc.Description)
+	}
+	return fmt.Sprintf("%s %s: %s", pc.Action, pc.Target, pc.Description)
+}
+
+// Plan returns the changes planned by the tasks of the run, in completion order. Tasks that planned
+// no changes are omitted.
+func (rs *RunSummary) Plan() []TaskPlan {
+	plans := make([]TaskPlan, 0)
+	for _, r := range rs.Results {
+		if len(r.Plan) == 0 {
+			continue
+		}
+		plans = append(plans, TaskPlan{Step: r.Step(), Name: r.Name, Task: r.Task, Changes: r.Plan})
+	}
+	return plans
+}
+
+// PlanString returns a human-readable description of a plan
+func PlanString(plans []TaskPlan) string {
+	var b strings.Builder
+	b.WriteString("* Plan\n")
+	if len(plans) == 0 {
+		b.WriteString("No changes planned.")
+		return b.String()
+	}
+
+	count := 0
+	for _, p := range plans {
+		if p.Name == "" {
+			b.WriteString(fmt.Sprintf("Task %s [%s]:\n", p.Step, p.Task))
+		} else {
+			b.WriteString(fmt.Sprintf("Task %s \"%s\" [%s]:\n", p.Step, p.Name, p.Task))
+		}
+		for _, c := range p.Changes {
+			b.WriteString(fmt.Sprintf("  - %s\n", c.String()))
+			count++
+		}
+	}
+	b.WriteString(fmt.Sprintf("%d change(s) planned by %d task(s).", count, len(plans)))
+	return b.String()
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package shared
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrPromptTimeout   = errors.New("timed out waiting for input")
+	ErrPromptCancelled = errors.New("cancelled while waiting for input")
+)
+
+// Lines are read from stdin by a single goroutine, so that a prompt that times out does not
+// consume the answer to the next prompt
+var (
+	stdinOnce  sync.Once
+	stdinLines chan string
+	stdinErr   error
+)
+
+// readStdin starts the goroutine that reads lines from stdin
+func readStdin() {
+	stdinLines = make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			stdinLines <- scanner.Text()
+		}
+		stdinErr = scanner.Err()
+		if stdinErr == nil {
+			stdinErr = io.EOF
+		}
+		close(stdinLines)
+	}()
+}
+
+// IsTerminal returns true if stdin is an interactive terminal
+//
+//goland:noinspection GoUnusedExportedFunction
+func IsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+
+	// The null device is a character device, but nobody is there to answer
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(fi, null) {
+		return false
+	}
+	return true
+}
+
+// Prompt writes a prompt to the console and reads a line from stdin. A timeout of zero waits
+// indefinitely. The cancel channel may be nil.
+//
+//goland:noinspection GoUnusedExportedFunction
+func Prompt(prompt string, timeout time.Duration, cancel <-chan struct{}) (string, error) {
+	stdinOnce.Do(readStdin)
+	Printf("%s", prompt)
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case line, ok := <-stdinLines:
+		if !ok {
+			Println()
+			return "", stdinErr
+		}
+		return strings.TrimSpace(line), nil
+	case <-expired:
+		Println()
+		return "", ErrPromptTimeout
+	case <-cancel:
+		Println()
+		return "", ErrPromptCancelled
+	}
+}
+
+// CurrentUser returns the name of the user running the program, taken from the environment
+//
+//goland:noinspection GoUnusedExportedFunction
+func CurrentUser() string {
+	for _, name := range []string{"SUDO_USER", "USER", "LOGNAME", "USERNAME"} {
+		if user := os.Getenv(name); user != "" {
+			return user
+		}
+	}
+	return "unknown"
+}
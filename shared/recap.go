@@ -0,0 +1,177 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package shared
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Recap summarizes the outcome of a workflow run in the style of an Ansible play recap
+type Recap struct {
+	RunID      string         `json:"run_id"`             // Unique ID of the workflow run
+	Success    bool           `json:"success"`            // Overall run success
+	Ok         int            `json:"ok"`                 // Tasks that succeeded (including those that changed something)
+	Changed    int            `json:"changed"`            // Tasks that succeeded and changed something
+	Failed     int            `json:"failed"`             // Tasks that failed
+	Skipped    int            `json:"skipped"`            // Tasks that were skipped
+	Ignored    int            `json:"ignored"`            // Tasks that failed but were ignored (ignore_errors)
+	Cancelled  int            `json:"cancelled"`          // Background tasks cancelled because the workflow failed
+	DurationMs int64          `json:"duration_ms"`        // Total run duration in milliseconds
+	Tasks      []RecapTask    `json:"tasks"`              // Per-task outcome and duration
+	Failures   []RecapFailure `json:"failures,omitempty"` // Failed tasks
+}
+
+// RecapTask is the outcome of a single task in a Recap
+type RecapTask struct {
+	Sequence   int    `json:"sequence"`
+	Phase      string `json:"phase,omitempty"`
+	Parent     string `json:"parent,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Task       string `json:"task"`
+	Status     string `json:"status"` // ok, changed, failed, ignored, cancelled, or skipped
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// RecapFailure describes a failed task in a Recap
+type RecapFailure struct {
+	Sequence     int    `json:"sequence"`
+	Phase        string `json:"phase,omitempty"`
+	Parent       string `json:"parent,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Task         string `json:"task"`
+	Msg          string `json:"msg"`
+	ErrorMessage string `json:"error_message,omitempty"`
+	Ignored      bool   `json:"ignored,omitempty"`
+}
+
+// Status returns a one-word status for a task result: ok, changed, failed, ignored, cancelled, or skipped
+func (tr *TaskResult) Status() string {
+	switch {
+	case tr.Skipped:
+		return "skipped"
+	case tr.Cancelled:
+		return "cancelled"
+	case !tr.Success && tr.Ignored:
+		return "ignored"
+	case !tr.Success:
+		return "failed"
+	case tr.Changed:
+		return "changed"
+	default:
+		return "ok"
+	}
+}
+
+// Recap computes a recap of the run from the collected task results
+func (rs *RunSummary) Recap() Recap {
+	recap := Recap{
+		RunID:      rs.RunID,
+		Success:    rs.Success,
+		DurationMs: rs.Duration().Milliseconds(),
+		Tasks:      make([]RecapTask, 0, len(rs.Results)),
+	}
+
+	for _, r := range rs.Results {
+		status := r.Status()
+		switch status {
+		case "skipped":
+			recap.Skipped++
+		case "cancelled":
+			recap.Cancelled++
+		case "failed", "ignored":
+			if r.Ignored {
+				recap.Ignored++
+			} else {
+				recap.Failed++
+			}
+			recap.Failures = append(recap.Failures, RecapFailure{
+				Sequence:     r.Sequence,
+				Phase:        r.Phase,
+				Parent:       r.Parent,
+				Name:         r.Name,
+				Task:         r.Task,
+				Msg:          r.Msg,
+				ErrorMessage: r.ErrorMessage,
+				Ignored:      r.Ignored,
+			})
+		case "changed":
+			recap.Changed++
+			recap.Ok++
+		default:
+			recap.Ok++
+		}
+
+		recap.Tasks = append(recap.Tasks, RecapTask{
+			Sequence:   r.Sequence,
+			Phase:      r.Phase,
+			Parent:     r.Parent,
+			Name:       r.Name,
+			Task:       r.Task,
+			Status:     status,
+			DurationMs: r.Duration().Milliseconds(),
+		})
+	}
+	return recap
+}
+
+// String returns a human-readable representation of the recap
+func (r *Recap) String() string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("* Recap (run %s)\n", r.RunID))
+	b.WriteString(fmt.Sprintf("ok=%d changed=%d failed=%d ignored=%d cancelled=%d skipped=%d duration=%s\n",
+		r.Ok, r.Changed, r.Failed, r.Ignored, r.Cancelled, r.Skipped, msToDuration(r.DurationMs)))
+
+	if len(r.Tasks) > 0 {
+		// Size the step column to the longest step so that the table lines up
+		width := 4
+		for _, t := range r.Tasks {
+			width = max(width, len(step(t.Phase, t.Parent, t.Sequence)))
+		}
+
+		b.WriteString("Tasks:\n")
+		for _, t := range r.Tasks {
+			b.WriteString(fmt.Sprintf("  %-*s %-9s %10s  %s\n", width, step(t.Phase, t.Parent, t.Sequence), t.Status, msToDuration(t.DurationMs), taskLabel(t.Name, t.Task)))
+		}
+	}
+
+	if len(r.Failures) > 0 {
+		b.WriteString("Failures:\n")
+		for _, f := range r.Failures {
+			label := taskLabel(f.Name, f.Task)
+			if f.Ignored {
+				label += " (ignored)"
+			}
+			b.WriteString(fmt.Sprintf("  %s %s: %s\n", step(f.Phase, f.Parent, f.Sequence), label, indentMsg(f.Msg)))
+		}
+	}
+
+	return TrimTrailingNewlines(b.String())
+}
+
+// indentMsg indents continuation lines of a multi-line message
+func indentMsg(msg string) string {
+	lines := strings.Split(TrimTrailingNewlines(msg), "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = "    " + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// taskLabel returns a human-readable label for a task
+func taskLabel(name, task string) string {
+	if name == "" {
+		return fmt.Sprintf("[%s]", task)
+	}
+	return fmt.Sprintf("\"%s\" [%s]", name, task)
+}
+
+// msToDuration converts milliseconds to a printable duration
+func msToDuration(ms int64) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
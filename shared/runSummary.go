@@ -0,0 +1,24 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package shared
+
+import "time"
+
+// RunSummary describes a completed workflow run, including every task result with timing information
+type RunSummary struct {
+	RunID     string       `json:"run_id"`         // Unique ID of the workflow run
+	Name      string       `json:"name,omitempty"` // Workflow name (or file name)
+	StartTime time.Time    `json:"start_time"`     // Time the run started
+	EndTime   time.Time    `json:"end_time"`       // Time the run ended
+	Success   bool         `json:"success"`        // Overall run success
+	Results   []TaskResult `json:"results"`        // Task results in completion order
+}
+
+// Duration returns the total duration of the run
+func (rs *RunSummary) Duration() time.Duration {
+	if rs.StartTime.IsZero() || rs.EndTime.IsZero() {
+		return 0
+	}
+	return rs.EndTime.Sub(rs.StartTime)
+}
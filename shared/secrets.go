@@ -0,0 +1,364 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// SecretPrefix marks a variable reference as a secret reference, e.g. {{secret:slack_token}} or
+// {{secret:ssm:/prod/slack/token}}
+const SecretPrefix = "secret:"
+
+// Redacted replaces secret values in output
+const Redacted = "[REDACTED]"
+
+// minRedactLength is the length below which a value is not redacted, because redacting very short values
+// would mangle unrelated output
+const minRedactLength = 4
+
+// Default secret providers
+const (
+	SecretProviderEnv  = "env"  // Environment variables
+	SecretProviderFile = "file" // Encrypted secrets file
+)
+
+// SecretProvider resolves the value of a secret from its name
+type SecretProvider interface {
+	Secret(name string) (string, error)
+}
+
+// EnvSecretProvider is implemented by secret providers that resolve secrets from the env file of the task,
+// such as env. Their values are resolved and cached separately for each env file.
+type EnvSecretProvider interface {
+	SecretProvider
+	EnvSecret(name string, env *Env) (string, error)
+}
+
+// SecretsConfig contains the workflow settings for secret references
+type SecretsConfig struct {
+	Provider string `yaml:"provider" json:"provider,omitempty"` // Provider of references without one, env by default
+	File     string `yaml:"file" json:"file,omitempty"`         // Encrypted secrets file for the file provider
+}
+
+var (
+	secretsMu       sync.RWMutex
+	secretProviders = make(map[string]SecretProvider)
+	secretsConfig   SecretsConfig
+	secretCache     = make(map[string]string)
+	secretValues    []string
+	redactor        *strings.Replacer
+)
+
+func init() {
+	RegisterSecretProvider(SecretProviderEnv, envSecrets{})
+	RegisterSecretProvider(SecretProviderFile, &fileSecrets{})
+}
+
+// RegisterSecretProvider registers a provider of secrets, e.g. ssm for AWS SSM Parameter Store
+//
+//goland:noinspection GoUnusedExportedFunction
+func RegisterSecretProvider(name string, provider SecretProvider) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	secretProviders[name] = provider
+}
+
+// ConfigureSecrets sets the secrets configuration and returns the previous one, so that a child workflow
+// can restore its parent's configuration
+//
+//goland:noinspection GoUnusedExportedFunction
+func ConfigureSecrets(cfg SecretsConfig) SecretsConfig {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	previous := secretsConfig
+	secretsConfig = cfg
+	return previous
+}
+
+// ResolveSecret returns the value of a secret reference, without the secret: prefix. The reference may
+// name its provider, e.g. ssm:/prod/token, otherwise the configured default provider is used. Providers that
+// read the environment resolve the secret from envFile, the env file of the task. Resolved values are cached
+// for the life of the process and redacted from all output.
+//
+//goland:noinspection GoUnusedExportedFunction
+func ResolveSecret(ref, envFile string) (string, error) {
+	secretsMu.RLock()
+	cfg := secretsConfig
+	secretsMu.RUnlock()
+
+	providerName, name, found := strings.Cut(ref, ":")
+	if !found {
+		providerName, name = cfg.Provider, ref
+		if providerName == "" {
+			providerName = SecretProviderEnv
+		}
+	}
+	if name == "" {
+		return "", fmt.Errorf("secret name is missing in %s%s", SecretPrefix, ref)
+	}
+
+	secretsMu.RLock()
+	provider, ok := secretProviders[providerName]
+	secretsMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("unknown secret provider %s in %s%s", providerName, SecretPrefix, ref)
+	}
+
+	// The same name may have a different value in each env file
+	key := providerName + ":" + name
+	envProvider, fromEnv := provider.(EnvSecretProvider)
+	if fromEnv {
+		key = envFile + "\x00" + key
+	}
+	secretsMu.RLock()
+	value, cached := secretCache[key]
+	secretsMu.RUnlock()
+	if cached {
+		return value, nil
+	}
+
+	var err error
+	if fromEnv {
+		var env *Env
+		if env, err = LoadEnv(envFile); err == nil {
+			value, err = envProvider.EnvSecret(name, env)
+		}
+	} else {
+		value, err = provider.Secret(name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve %s%s: %w", SecretPrefix, ref, err)
+	}
+
+	AddSecret(value)
+	secretsMu.Lock()
+	secretCache[key] = value
+	secretsMu.Unlock()
+	return value, nil
+}
+
+// ResolveSecrets resolves the secret references in the strings of a value, which may be a string, map, or
+// list, with the env file of the task, and returns the first error. It is used to fail a task whose secrets
+// cannot be resolved before it runs, as variable processing replaces an unresolvable reference with an
+// empty string.
+//
+//goland:noinspection GoUnusedExportedFunction
+func ResolveSecrets(v any, envFile string) error {
+	switch value := v.(type) {
+	case string:
+		for _, ref := range secretRefs(value) {
+			if _, err := ResolveSecret(ref, envFile); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		for _, item := range value {
+			if err := ResolveSecrets(item, envFile); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range value {
+			if err := ResolveSecrets(item, envFile); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// secretRefs returns the secret references in a string, without the secret: prefix
+func secretRefs(s string) []string {
+	var refs []string
+	for {
+		start := strings.Index(s, "{{"+SecretPrefix)
+		if start == -1 {
+			return refs
+		}
+		end := strings.Index(s[start:], "}}")
+		if end == -1 {
+			return refs
+		}
+		refs = append(refs, s[start+2+len(SecretPrefix):start+end])
+		s = s[start+end+2:]
+	}
+}
+
+// AddSecret records a value that must be redacted from all output. Multi-line values are also redacted
+// line by line, as they may be reformatted (e.g. indented) in output. Values shorter than four characters
+// are not redacted.
+//
+//goland:noinspection GoUnusedExportedFunction
+func AddSecret(value string) {
+	candidates := []string{value}
+	if strings.Contains(value, "\n") {
+		candidates = append(candidates, strings.Split(value, "\n")...)
+	}
+
+	// The value may also appear escaped in JSON output
+	var forms []string
+	for _, c := range candidates {
+		c = strings.TrimSpace(c)
+		if len(c) < minRedactLength {
+			continue
+		}
+		forms = append(forms, c)
+		if escaped, err := json.Marshal(c); err == nil && string(escaped[1:len(escaped)-1]) != c {
+			forms = append(forms, string(escaped[1:len(escaped)-1]))
+		}
+	}
+
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	changed := false
+	for _, form := range forms {
+		if !slices.Contains(secretValues, form) {
+			secretValues = append(secretValues, form)
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	// Longer values are replaced first, so that a secret containing another is fully redacted
+	slices.SortFunc(secretValues, func(a, b string) int { return len(b) - len(a) })
+	pairs := make([]string, 0, len(secretValues)*2)
+	for _, v := range secretValues {
+		pairs = append(pairs, v, Redacted)
+	}
+	redactor = strings.NewReplacer(pairs...)
+}
+
+// Redact replaces any secret values in a string, including values inside larger strings
+//
+//goland:noinspection GoUnusedExportedFunction
+func Redact(s string) string {
+	secretsMu.RLock()
+	r := redactor
+	secretsMu.RUnlock()
+	if r == nil {
+		return s
+	}
+	return r.Replace(s)
+}
+
+// RedactAny returns a copy of a value with secret values replaced in all strings. Maps and lists are
+// processed recursively. Other values, such as structs returned by tasks, are converted to their JSON
+// representation if it contains a secret value.
+//
+//goland:noinspection GoUnusedExportedFunction
+func RedactAny(v any) any {
+	switch value := v.(type) {
+	case string:
+		return Redact(value)
+	case map[string]any:
+		if value == nil {
+			return v
+		}
+		m := make(map[string]any, len(value))
+		for k, item := range value {
+			m[k] = RedactAny(item)
+		}
+		return m
+	case []any:
+		if value == nil {
+			return v
+		}
+		l := make([]any, len(value))
+		for i, item := range value {
+			l[i] = RedactAny(item)
+		}
+		return l
+	case nil, bool, int, int64, float64:
+		return v
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return v
+		}
+		if redacted := Redact(string(data)); redacted != string(data) {
+			var out any
+			if err = json.Unmarshal([]byte(redacted), &out); err == nil {
+				return out
+			}
+			return Redacted
+		}
+		return v
+	}
+}
+
+// envSecrets resolves secrets from the env file of the task or the process environment, e.g.
+// {{secret:env:GITHUB_TOKEN}}
+type envSecrets struct{}
+
+func (p envSecrets) Secret(name string) (string, error) {
+	return p.EnvSecret(name, &Env{})
+}
+
+func (envSecrets) EnvSecret(name string, env *Env) (string, error) {
+	if value, ok := env.Lookup(name); ok {
+		return value, nil
+	}
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// fileSecrets resolves secrets from an encrypted JSON object of names and values, e.g. {{secret:file:token}}.
+// The file is set in the workflow's secrets settings or OPSBLADE_SECRETS_FILE, and is decrypted with the
+// passphrase described in Passphrase, which may be set in the task's env file.
+type fileSecrets struct {
+	mu      sync.Mutex
+	file    string
+	secrets map[string]string
+}
+
+func (f *fileSecrets) Secret(name string) (string, error) {
+	return f.EnvSecret(name, nil)
+}
+
+func (f *fileSecrets) EnvSecret(name string, env *Env) (string, error) {
+	secretsMu.RLock()
+	file := secretsConfig.File
+	secretsMu.RUnlock()
+	if file == "" {
+		file = os.Getenv("OPSBLADE_SECRETS_FILE")
+	}
+	if file == "" {
+		return "", fmt.Errorf("no secrets file is set")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file != file {
+		passphrase, err := Passphrase("", "", env)
+		if err != nil {
+			return "", err
+		}
+		data, err := ReadEncryptedFile(file, passphrase)
+		if err != nil {
+			return "", err
+		}
+		var secrets map[string]string
+		if err = json.Unmarshal(data, &secrets); err != nil {
+			return "", fmt.Errorf("secrets file %s is not a JSON object of strings: %w", file, err)
+		}
+		f.file, f.secrets = file, secrets
+	}
+
+	value, ok := f.secrets[name]
+	if !ok {
+		return "", fmt.Errorf("secret %s is not in %s", name, file)
+	}
+	return value, nil
+}
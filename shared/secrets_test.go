@@ -0,0 +1,190 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package shared
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testSecrets is a secret provider that returns the value in its map
+type testSecrets map[string]string
+
+func (p testSecrets) Secret(name string) (string, error) {
+	value, ok := p[name]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return value, nil
+}
+
+// TestRedact tests that secret values are redacted wherever they appear in a string, including inside larger
+// strings, escaped in JSON, and line by line for multi-line values
+func TestRedact(t *testing.T) {
+	AddSecret("tok-5d1f9a")
+	AddSecret("tok-5d1f9a-extended")
+	AddSecret("pass\"word\\5d1f")
+	AddSecret("-----BEGIN KEY-----\nMIIB5d1f\n-----END KEY-----")
+	AddSecret("5d1")
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"whole value", "tok-5d1f9a", Redacted},
+		{"inside a command line", "curl -H 'Authorization: Bearer tok-5d1f9a' https://example.com",
+			"curl -H 'Authorization: Bearer " + Redacted + "' https://example.com"},
+		{"inside a word", "xtok-5d1f9ax", "x" + Redacted + "x"},
+		{"repeated", "tok-5d1f9a,tok-5d1f9a", Redacted + "," + Redacted},
+		{"longer secret containing another", "tok-5d1f9a-extended", Redacted},
+		{"escaped in JSON", `{"password":"pass\"word\\5d1f"}`, `{"password":"` + Redacted + `"}`},
+		{"line of a multi-line value", "key:\n  MIIB5d1f\n", "key:\n  " + Redacted + "\n"},
+		{"short values are not redacted", "5d1", "5d1"},
+		{"no secret", "nothing to hide", "nothing to hide"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.in); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Structured values are redacted recursively, and structs through their JSON representation
+	type taskData struct {
+		Args []string `json:"args"`
+	}
+	got := RedactAny(map[string]any{
+		"cmd":   "deploy --token=tok-5d1f9a",
+		"list":  []any{"tok-5d1f9a", 42},
+		"data":  taskData{Args: []string{"--password", "pass\"word\\5d1f"}},
+		"count": 3,
+	})
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(data); strings.Contains(s, "5d1f") || strings.Count(s, Redacted) != 3 || !strings.Contains(s, `"count":3`) {
+		t.Errorf("not redacted as expected: %s", s)
+	}
+}
+
+// TestResolveSecret tests that resolved secrets are cached and redacted, and that a reference without a
+// provider uses the configured default
+func TestResolveSecret(t *testing.T) {
+	provider := testSecrets{"deploy": "deploy-7c2e4b", "jira": "jira-7c2e4b"}
+	RegisterSecretProvider("test", provider)
+	defer ConfigureSecrets(ConfigureSecrets(SecretsConfig{Provider: "test"}))
+
+	for ref, want := range map[string]string{"test:deploy": "deploy-7c2e4b", "jira": "jira-7c2e4b"} {
+		value, err := ResolveSecret(ref, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != want {
+			t.Errorf("%s: got %q, want %q", ref, value, want)
+		}
+		if Redact("value="+value) != "value="+Redacted {
+			t.Errorf("%s is not redacted", ref)
+		}
+	}
+
+	// The value is cached, so it is resolved once
+	delete(provider, "deploy")
+	if value, err := ResolveSecret("test:deploy", ""); err != nil || value != "deploy-7c2e4b" {
+		t.Errorf("the resolved value was not cached: %q, %v", value, err)
+	}
+
+	for _, ref := range []string{"test:missing", "unknown:deploy", "test:"} {
+		if _, err := ResolveSecret(ref, ""); err == nil {
+			t.Errorf("%s: expected an error", ref)
+		}
+	}
+	err := ResolveSecrets(map[string]any{"args": []any{"ok", "{{secret:test:missing}}"}}, "")
+	if err == nil || !strings.Contains(err.Error(), "secret:test:missing") {
+		t.Errorf("expected the unresolvable reference in a list to fail, got %v", err)
+	}
+}
+
+// TestEnvSecret tests that env secrets are resolved from the env file of the task, falling back to the process
+// environment, and that a value read from one env file is not used for another
+func TestEnvSecret(t *testing.T) {
+	dir := t.TempDir()
+	prod, dev := filepath.Join(dir, "prod.env"), filepath.Join(dir, "dev.env")
+	if err := os.WriteFile(prod, []byte("ENV_SECRET_TOKEN=prod-token-4f0e\nENV_SECRET_ONLY_PROD=prod-only-4f0e\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dev, []byte("ENV_SECRET_TOKEN=dev-token-4f0e\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ENV_SECRET_ONLY_PROD", "process-4f0e")
+
+	tests := []struct {
+		ref     string
+		envFile string
+		want    string
+	}{
+		{"env:ENV_SECRET_TOKEN", prod, "prod-token-4f0e"},
+		{"env:ENV_SECRET_TOKEN", dev, "dev-token-4f0e"},
+		{"ENV_SECRET_TOKEN", dev, "dev-token-4f0e"},
+		{"env:ENV_SECRET_ONLY_PROD", prod, "prod-only-4f0e"},
+		{"env:ENV_SECRET_ONLY_PROD", dev, "process-4f0e"},
+		{"env:ENV_SECRET_ONLY_PROD", "", "process-4f0e"},
+	}
+	for _, tt := range tests {
+		value, err := ResolveSecret(tt.ref, tt.envFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != tt.want {
+			t.Errorf("%s in %s: got %q, want %q", tt.ref, filepath.Base(tt.envFile), value, tt.want)
+		}
+	}
+
+	if _, err := ResolveSecret("env:ENV_SECRET_MISSING", dev); err == nil {
+		t.Error("expected an error for a variable that is not set")
+	}
+	if _, err := ResolveSecret("env:ENV_SECRET_TOKEN", filepath.Join(dir, "missing.env")); err == nil {
+		t.Error("expected an error for an env file that does not exist")
+	}
+}
+
+// TestDumpTaskRedacted tests that DumpTask and the task output redact secret values
+func TestDumpTaskRedacted(t *testing.T) {
+	AddSecret("dump-3e8a7f")
+	var buf bytes.Buffer
+	defer func(previous io.Writer) { Console = previous }(Console)
+	Console = &buf
+
+	DumpTask(&struct {
+		Context TaskContext
+		Cmd     string
+		Args    []string
+	}{
+		Context: TaskContext{Name: "deploy", Task: "cmd_exec"},
+		Cmd:     "curl",
+		Args:    []string{"-H", "Authorization: Bearer dump-3e8a7f"},
+	})
+	if s := buf.String(); strings.Contains(s, "dump-3e8a7f") || !strings.Contains(s, "Bearer "+Redacted) {
+		t.Errorf("DumpTask output is not redacted: %s", s)
+	}
+
+	context := TaskContext{Name: "deploy", Task: "cmd_exec"}
+	result := context.Result(true, "sent dump-3e8a7f", map[string]any{"stdout": "token dump-3e8a7f accepted"})
+	for _, s := range []string{result.String(), result.Serialize(), result.SerializePretty()} {
+		if strings.Contains(s, "dump-3e8a7f") {
+			t.Errorf("the result is not redacted: %s", s)
+		}
+	}
+	if r := result.Redacted(); strings.Contains(r.Msg+Dump(r.Data), "dump-3e8a7f") {
+		t.Errorf("the redacted result contains the secret: %+v", r)
+	}
+}
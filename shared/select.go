@@ -218,6 +218,18 @@ func matchesCriteria(value any, criteria SelectCriteria) bool {
 		default:
 			return false
 		}
+	case []any:
+		// A list contains the value if any of its elements equals it
+		if criteria.Compare.ToLower() != Contains {
+			return false
+		}
+		equal := SelectCriteria{Field: criteria.Field, Value: criteria.Value, Compare: Equals}
+		for _, elem := range typedVal {
+			if matchesCriteria(elem, equal) {
+				return true
+			}
+		}
+		return false
 	case bool:
 		wanted, ok := criteria.Value.(bool)
 		if !ok {
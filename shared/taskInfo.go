@@ -6,17 +6,23 @@ package shared
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 )
 
 // TaskInfo is used to report the start of a task
 type TaskInfo struct {
-	MessageType  string         `json:"message_type"  yaml:"message_type"`  // Message type
-	Sequence     int            `json:"sequence"      yaml:"sequence"`      // Task sequence number
-	Name         string         `json:"name"          yaml:"name"`          // Task name
-	Task         string         `json:"task"          yaml:"task"`          // Task type
-	Msg          string         `json:"msg,omitempty" yaml:"msg,omitempty"` // Task message (used primarily for errors)
-	Instructions map[string]any `json:"instructions"  yaml:"instructions"`  // Task data
-	Debug        bool           `json:"debug"         yaml:"debug"`         // Debug flag
+	MessageType  string         `json:"message_type"  yaml:"message_type"`                  // Message type
+	Phase        string         `json:"phase,omitempty" yaml:"phase,omitempty"`             // Workflow phase (empty for the main task list)
+	Parent       string         `json:"parent,omitempty" yaml:"parent,omitempty"`           // Position of the parent task for nested tasks
+	Sequence     int            `json:"sequence"      yaml:"sequence"`                      // Task sequence number
+	Name         string         `json:"name"          yaml:"name"`                          // Task name
+	Task         string         `json:"task"          yaml:"task"`                          // Task type
+	Environment  string         `json:"environment,omitempty" yaml:"environment,omitempty"` // Selected environment
+	Msg          string         `json:"msg,omitempty" yaml:"msg,omitempty"`                 // Task message (used primarily for errors)
+	Instructions map[string]any `json:"instructions"  yaml:"instructions"`                  // Task data
+	Debug        bool           `json:"debug"         yaml:"debug"`                         // Debug flag
+	RunID        string         `json:"run_id,omitempty"    yaml:"run_id,omitempty"`        // Unique ID of the workflow run
+	Timestamp    time.Time      `json:"timestamp,omitzero"  yaml:"timestamp,omitempty"`     // Time the task started
 }
 
 // serialize is a non-exported function that attempts to serialize the task result to a JSON string
@@ -24,8 +30,12 @@ func (ti *TaskInfo) serialize(prefix, indent string) string {
 	var err error
 	var data []byte
 
-	// Serialize the task result
-	data, err = json.MarshalIndent(ti, prefix, indent)
+	// Serialize the task result. MarshalIndent always inserts newlines, so use Marshal for compact output.
+	if prefix == "" && indent == "" {
+		data, err = json.Marshal(ti)
+	} else {
+		data, err = json.MarshalIndent(ti, prefix, indent)
+	}
 	if err != nil {
 		// If serialization fails, return an error result
 		msg := fmt.Sprintf("error serializing task info: %s", err.Error())
@@ -41,7 +51,7 @@ func (ti *TaskInfo) serialize(prefix, indent string) string {
 			data = []byte{}
 		}
 	}
-	return string(data)
+	return Redact(string(data))
 }
 
 // Serialize the task result to a JSON string
@@ -59,10 +69,14 @@ func (ti *TaskInfo) String() string {
 	var r string
 
 	if ti.Name == "" {
-		r = fmt.Sprintf("* Starting task %d: [%s]\n", ti.Sequence, ti.Task)
+		r = fmt.Sprintf("* Starting %s: [%s]", phaseTask(ti.Phase, ti.Parent, ti.Sequence), ti.Task)
 	} else {
-		r = fmt.Sprintf("* Starting task %d: \"%s\" [%s]\n", ti.Sequence, ti.Name, ti.Task)
+		r = fmt.Sprintf("* Starting %s: \"%s\" [%s]", phaseTask(ti.Phase, ti.Parent, ti.Sequence), ti.Name, ti.Task)
+	}
+	if ti.Environment != "" {
+		r += fmt.Sprintf(" in %s", ti.Environment)
 	}
+	r += "\n"
 
 	if ti.Debug {
 		if ti.Instructions != nil {
@@ -77,5 +91,14 @@ func (ti *TaskInfo) String() string {
 
 	// Remove any trailing newline characters
 	r = TrimTrailingNewlines(r)
-	return r
+	return Redact(r)
+}
+
+// Redacted returns a copy of the task information with secret values replaced
+func (ti TaskInfo) Redacted() TaskInfo {
+	ti.Msg = Redact(ti.Msg)
+	if instructions, ok := RedactAny(ti.Instructions).(map[string]any); ok {
+		ti.Instructions = instructions
+	}
+	return ti
 }
@@ -6,18 +6,33 @@ package shared
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 )
 
 // TaskResult is used to report on the result of a task
 type TaskResult struct {
-	MessageType string         `json:"message_type"`   // Message type
-	Success     bool           `json:"success"`        // Task success status
-	Msg         string         `json:"msg,omitempty"`  // Task message
-	Sequence    int            `json:"sequence"`       // Task sequence number
-	Name        string         `json:"name,omitempty"` // Task name
-	Task        string         `json:"task,omitempty"` // Task type
-	Data        map[string]any `json:"data,omitempty"` // Task data
-	NoVars      bool           `json:"-" yaml:"-"`     // Do not set variables from this data
+	MessageType  string          `json:"message_type"`            // Message type
+	Phase        string          `json:"phase,omitempty"`         // Workflow phase (empty for the main task list)
+	Parent       string          `json:"parent,omitempty"`        // Position of the parent task for nested tasks (e.g. "3" for tasks of a macro invoked by task 3)
+	Success      bool            `json:"success"`                 // Task success status
+	Msg          string          `json:"msg,omitempty"`           // Task message
+	Sequence     int             `json:"sequence"`                // Task sequence number
+	Name         string          `json:"name,omitempty"`          // Task name
+	Task         string          `json:"task,omitempty"`          // Task type
+	Data         map[string]any  `json:"data,omitempty"`          // Task data
+	Changed      bool            `json:"changed"`                 // Task changed something
+	Plan         []PlannedChange `json:"plan,omitempty"`          // Changes the task would make (plan mode)
+	AWSIdentity  *AWSIdentity    `json:"aws_identity,omitempty"`  // AWS account and region used, if aws_guard is set or the task is audited
+	JiraUser     string          `json:"jira_user,omitempty"`     // Jira user the task acted as
+	Skipped      bool            `json:"skipped,omitempty"`       // Task was skipped
+	Ignored      bool            `json:"ignored,omitempty"`       // Task failed but ignore_errors allowed the workflow to continue
+	Cancelled    bool            `json:"cancelled,omitempty"`     // Background task was cancelled because the workflow failed
+	TaskSuccess  bool            `json:"task_success"`            // Success as reported by the task, before failed_when and ignore_errors
+	ErrorMessage string          `json:"error_message,omitempty"` // Custom error message from the task definition
+	RunID        string          `json:"run_id,omitempty"`        // Unique ID of the workflow run
+	StartTime    time.Time       `json:"start_time,omitzero"`     // Time the task started
+	Timestamp    time.Time       `json:"timestamp,omitzero"`      // Time the task completed
+	NoVars       bool            `json:"-" yaml:"-"`              // Do not set variables from this data
 }
 
 // serialize is a non-exported function that attempts to serialize the task result to a JSON string
@@ -25,8 +40,12 @@ func (tr *TaskResult) serialize(prefix, indent string) string {
 	var err error
 	var data []byte
 
-	// Serialize the task result
-	data, err = json.MarshalIndent(tr, prefix, indent)
+	// Serialize the task result. MarshalIndent always inserts newlines, so use Marshal for compact output.
+	if prefix == "" && indent == "" {
+		data, err = json.Marshal(tr)
+	} else {
+		data, err = json.MarshalIndent(tr, prefix, indent)
+	}
 	if err != nil {
 		// If serialization fails, return an error result
 		msg := fmt.Sprintf("error serializing task result: %s", err.Error())
@@ -43,7 +62,7 @@ func (tr *TaskResult) serialize(prefix, indent string) string {
 			data = []byte{}
 		}
 	}
-	return string(data)
+	return Redact(string(data))
 }
 
 // Serialize the task result to a JSON string
@@ -56,17 +75,41 @@ func (tr *TaskResult) SerializePretty() string {
 	return tr.serialize("", "  ")
 }
 
+// Duration returns the time taken by the task, or zero if timing information is not available
+func (tr *TaskResult) Duration() time.Duration {
+	if tr.StartTime.IsZero() || tr.Timestamp.IsZero() {
+		return 0
+	}
+	return tr.Timestamp.Sub(tr.StartTime)
+}
+
 // String attempts to return a human-readable string representation of the task result
 func (tr *TaskResult) String() string {
 	var r string
 
 	if tr.Name == "" {
-		r = fmt.Sprintf("* Completed task %d: [%s]\n", tr.Sequence, tr.Task)
+		r = fmt.Sprintf("* Completed %s: [%s]\n", phaseTask(tr.Phase, tr.Parent, tr.Sequence), tr.Task)
 	} else {
-		r = fmt.Sprintf("* Completed task %d: \"%s\" [%s]\n", tr.Sequence, tr.Name, tr.Task)
+		r = fmt.Sprintf("* Completed %s: \"%s\" [%s]\n", phaseTask(tr.Phase, tr.Parent, tr.Sequence), tr.Name, tr.Task)
 	}
 	r += fmt.Sprintf("Success: %t\n", tr.Success)
+	if tr.TaskSuccess != tr.Success {
+		r += fmt.Sprintf("Task success: %t\n", tr.TaskSuccess)
+	}
+	if tr.Ignored {
+		r += "Ignored: true\n"
+	}
+	r += fmt.Sprintf("Changed: %t\n", tr.Changed)
 	r += fmt.Sprintf("Message: %s\n", tr.Msg)
+	if tr.AWSIdentity != nil {
+		r += fmt.Sprintf("AWS: %s\n", tr.AWSIdentity.String())
+	}
+	if len(tr.Plan) > 0 {
+		r += "Plan:\n"
+		for _, c := range tr.Plan {
+			r += fmt.Sprintf("  - %s\n", c.String())
+		}
+	}
 	if tr.Data != nil {
 		if len(tr.Data) > 0 {
 			r += fmt.Sprintf("Data:\n")
@@ -78,5 +121,59 @@ func (tr *TaskResult) String() string {
 
 	// Remove any trailing newline characters
 	r = TrimTrailingNewlines(r)
-	return r
+	return Redact(r)
+}
+
+// Redacted returns a copy of the task result with secret values replaced
+func (tr TaskResult) Redacted() TaskResult {
+	tr.Msg = Redact(tr.Msg)
+	tr.ErrorMessage = Redact(tr.ErrorMessage)
+	if data, ok := RedactAny(tr.Data).(map[string]any); ok {
+		tr.Data = data
+	}
+	if tr.Plan != nil {
+		plan := make([]PlannedChange, len(tr.Plan))
+		for i, c := range tr.Plan {
+			c.Target = Redact(c.Target)
+			c.Description = Redact(c.Description)
+			if details, ok := RedactAny(c.Details).(map[string]any); ok {
+				c.Details = details
+			}
+			plan[i] = c
+		}
+		tr.Plan = plan
+	}
+	return tr
+}
+
+// Step returns the position of the task within the workflow, prefixed by the phase if it is not
+// part of the main task list (e.g. "3", "3.1" for the first task of a macro invoked by task 3,
+// or "on_failure.1")
+func (tr *TaskResult) Step() string {
+	return step(tr.Phase, tr.Parent, tr.Sequence)
+}
+
+// NestedStep returns the position of a task within its phase, given the position of its parent
+// task (empty for a top-level task)
+func NestedStep(parent string, sequence int) string {
+	if parent == "" {
+		return fmt.Sprintf("%d", sequence)
+	}
+	return fmt.Sprintf("%s.%d", parent, sequence)
+}
+
+// step returns a task position, prefixed by the phase if one is set
+func step(phase, parent string, sequence int) string {
+	if phase == "" {
+		return NestedStep(parent, sequence)
+	}
+	return fmt.Sprintf("%s.%s", phase, NestedStep(parent, sequence))
+}
+
+// phaseTask returns a human-readable description of the task position, such as "task 3.1" or "on_failure task 1"
+func phaseTask(phase, parent string, sequence int) string {
+	if phase == "" {
+		return fmt.Sprintf("task %s", NestedStep(parent, sequence))
+	}
+	return fmt.Sprintf("%s task %s", phase, NestedStep(parent, sequence))
 }
@@ -5,6 +5,7 @@ package shared
 
 import (
 	"fmt"
+	"time"
 )
 
 type Task interface {
@@ -12,22 +13,75 @@ type Task interface {
 }
 
 type TaskContext struct {
-	Env          string `json:"env,omitempty"`          // Task environment (overrides global)
-	DryRun       bool   `json:"dryrun,omitempty"`       // Dry run mode
-	Debug        bool   `json:"debug,omitempty"`        // Debug mode
-	Name         string `json:"name,omitempty"`         // Task name
-	Task         string `json:"task"`                   // Task type
-	Sequence     int    `json:"sequence"`               // Task sequence number
-	Instructions []byte `json:"instructions,omitempty"` // Task instructions
-	ErrorMessage string `json:"error_message,omitempty"` // Custom error message to display on failure
+	Env          string          `json:"env,omitempty"`           // Task environment (overrides global)
+	DryRun       bool            `json:"dryrun,omitempty"`        // Dry run mode
+	Debug        bool            `json:"debug,omitempty"`         // Debug mode
+	Name         string          `json:"name,omitempty"`          // Task name
+	Task         string          `json:"task"`                    // Task type
+	Sequence     int             `json:"sequence"`                // Task sequence number
+	Instructions []byte          `json:"instructions,omitempty"`  // Task instructions
+	ErrorMessage string          `json:"error_message,omitempty"` // Custom error message to display on failure
+	Cancel       <-chan struct{} `json:"-"`                       // Closed if a background task is cancelled
+	AWSGuard     *AWSGuard       `json:"-"`                       // AWS accounts and regions the task may use
+	AWSIdentity  *AWSIdentity    `json:"aws_identity,omitempty"`  // AWS identity resolved by the guard
+	JiraUser     string          `json:"jira_user,omitempty"`     // Jira user the task acts as
+	AWSProfile   string          `json:"aws_profile,omitempty"`   // AWS profile if the task does not set one
+	AWSRegion    string          `json:"aws_region,omitempty"`    // AWS region if the task does not set one
+	Audit        bool            `json:"-"`                       // The task is recorded in the audit log
+}
+
+// Sleep pauses the task for the specified duration. Long-running tasks should use it rather than
+// time.Sleep so that they can be cancelled. It returns false if the task was cancelled.
+func (c *TaskContext) Sleep(d time.Duration) bool {
+	if c.Cancel == nil {
+		time.Sleep(d)
+		return true
+	}
+	select {
+	case <-time.After(d):
+		return true
+	case <-c.Cancel:
+		return false
+	}
 }
 
 var TaskRegistry = make(map[string]func(TaskContext) Task)
 
+// TaskMetadata describes a registered task
+type TaskMetadata struct {
+	Mutating bool // The task changes something, so it is refused in read-only mode
+}
+
+// TaskMeta contains the metadata of each registered task
+var TaskMeta = make(map[string]TaskMetadata)
+
+// TaskOption is used for the golang options pattern when registering a task
+type TaskOption func(*TaskMetadata)
+
+// Mutating marks a task as one that changes something, such as starting an instance or creating a Jira
+// issue. Tasks that only read and report are not marked.
+//
+//goland:noinspection GoUnusedExportedFunction
+func Mutating() TaskOption {
+	return func(m *TaskMetadata) {
+		m.Mutating = true
+	}
+}
+
 // RegisterTask registers a task constructor with the task registry
 // A unique taskID is required for each task.
-func RegisterTask(taskID string, constructor func(TaskContext) Task) {
+func RegisterTask(taskID string, constructor func(TaskContext) Task, options ...TaskOption) {
+	var meta TaskMetadata
+	for _, opt := range options {
+		opt(&meta)
+	}
 	TaskRegistry[taskID] = constructor
+	TaskMeta[taskID] = meta
+}
+
+// IsMutating returns true if a registered task changes something
+func IsMutating(taskID string) bool {
+	return TaskMeta[taskID].Mutating
 }
 
 func (c *TaskContext) String() string {
@@ -44,7 +98,9 @@ func (c *TaskContext) Result(success bool, msg string, data any) TaskResult {
 		Sequence:    c.Sequence,
 		Name:        c.Name,
 		Task:        c.Task,
-		Data:        dataMap}
+		Data:        dataMap,
+		AWSIdentity: c.AWSIdentity,
+		JiraUser:    c.JiraUser}
 }
 
 // Error returns a task result error enriched with information from the task context
@@ -55,11 +111,13 @@ func (c *TaskContext) Error(msg string, err error) TaskResult {
 	} else {
 		fullMsg = fmt.Sprintf("%s: %s", msg, err.Error())
 	}
-	
+
 	// Append custom error message if provided
 	if c.ErrorMessage != "" {
 		fullMsg = fmt.Sprintf("%s\n\n%s\n", fullMsg, c.ErrorMessage)
 	}
-	
-	return c.Result(false, fullMsg, nil)
+
+	r := c.Result(false, fullMsg, nil)
+	r.ErrorMessage = c.ErrorMessage
+	return r
 }
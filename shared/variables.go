@@ -5,18 +5,25 @@ package shared
 
 import (
 	"fmt"
+	"maps"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 )
 
+// Variables holds the workflow variables. Access it through the functions in this file, which are
+// safe to use from tasks running in the background.
 var Variables = make(map[string]any)
 
+// varsMu protects Variables
+var varsMu sync.RWMutex
+
 // GetVar returns the value of a variable
 //
 //goland:noinspection GoUnusedExportedFunction
 func GetVar(name string) any {
-	if value, ok := Variables[name]; ok {
+	if value, ok := LookupVar(name); ok {
 		return value
 	}
 	return nil
@@ -24,7 +31,7 @@ func GetVar(name string) any {
 
 //goland:noinspection GoUnusedExportedFunction
 func GetVarString(name string) string {
-	if value, ok := Variables[name]; ok {
+	if value, ok := LookupVar(name); ok {
 		return AnyToString(value)
 	}
 	return ""
@@ -32,7 +39,7 @@ func GetVarString(name string) string {
 
 //goland:noinspection GoUnusedExportedFunction
 func GetVarInt(name string) int {
-	if value, ok := Variables[name]; ok {
+	if value, ok := LookupVar(name); ok {
 		return AnyToInt(value)
 	}
 	return 0
@@ -40,7 +47,7 @@ func GetVarInt(name string) int {
 
 //goland:noinspection GoUnusedExportedFunction
 func GetVarInt64(name string) int64 {
-	if value, ok := Variables[name]; ok {
+	if value, ok := LookupVar(name); ok {
 		return AnyToInt64(value)
 	}
 	return 0
@@ -48,7 +55,7 @@ func GetVarInt64(name string) int64 {
 
 //goland:noinspection GoUnusedExportedFunction
 func GetVarBool(name string) bool {
-	if value, ok := Variables[name]; ok {
+	if value, ok := LookupVar(name); ok {
 		return AnyToBool(value)
 	}
 	return false
@@ -56,7 +63,7 @@ func GetVarBool(name string) bool {
 
 //goland:noinspection GoUnusedExportedFunction
 func GetVarMapString(name string) map[string]string {
-	if value, ok := Variables[name]; ok {
+	if value, ok := LookupVar(name); ok {
 		return AnyToMapString(value)
 	}
 	return make(map[string]string)
@@ -64,68 +71,151 @@ func GetVarMapString(name string) map[string]string {
 
 //goland:noinspection GoUnusedExportedFunction
 func GetVarList(name string) []string {
-	if value, ok := Variables[name]; ok {
+	if value, ok := LookupVar(name); ok {
 		return AnyToList(value)
 	}
 	return nil
 }
 
+// LookupVar returns the value of a variable and whether it exists
+func LookupVar(name string) (any, bool) {
+	varsMu.RLock()
+	defer varsMu.RUnlock()
+	value, ok := Variables[name]
+	return value, ok
+}
+
+// GetVars returns a copy of all variables
+//
 //goland:noinspection GoUnusedExportedFunction
 func GetVars() map[string]any {
-	return Variables
+	varsMu.RLock()
+	defer varsMu.RUnlock()
+	return maps.Clone(Variables)
 }
 
 //goland:noinspection GoUnusedExportedFunction
 func SetVar(name string, value any) {
+	varsMu.Lock()
+	defer varsMu.Unlock()
 	Variables[name] = value
 }
 
+// SwapVars replaces all variables with the provided map and returns the previous variables. It is used
+// to give a child workflow its own variable scope.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SwapVars(vars map[string]any) map[string]any {
+	if vars == nil {
+		vars = make(map[string]any)
+	}
+	varsMu.Lock()
+	defer varsMu.Unlock()
+	previous := Variables
+	Variables = vars
+	return previous
+}
+
+// UnsetVar removes a variable
+//
+//goland:noinspection GoUnusedExportedFunction
+func UnsetVar(name string) {
+	varsMu.Lock()
+	defer varsMu.Unlock()
+	delete(Variables, name)
+}
+
+// ResolveVars returns a copy of a value with any {{...}} placeholders in strings replaced with their
+// values. Maps and lists are processed recursively. Unlike ProcessVars, non-string values are preserved.
+// Secret references are kept, so that they are resolved with the env file of the task that uses the value.
+func ResolveVars(v any) any {
+	switch value := v.(type) {
+	case string:
+		return replaceVarsInString(value, nil)
+	case map[string]any:
+		m := make(map[string]any, len(value))
+		for k, item := range value {
+			m[k] = ResolveVars(item)
+		}
+		return m
+	case []any:
+		l := make([]any, len(value))
+		for i, item := range value {
+			l[i] = ResolveVars(item)
+		}
+		return l
+	default:
+		return v
+	}
+}
+
+// secretResolver returns the value of a secret reference
+type secretResolver func(ref string) string
+
 // ProcessVars processes the variables in a struct, replacing any {{...}} placeholders with their values.
+// Secret references are resolved with the env file in the task context of the struct, if it has one.
 func ProcessVars(v any) {
+	envFile := ""
+	if val := reflect.ValueOf(v).Elem(); val.Kind() == reflect.Struct {
+		if field := val.FieldByName("Context"); field.IsValid() && field.CanInterface() {
+			if context, ok := field.Interface().(TaskContext); ok {
+				envFile = context.Env
+			}
+		}
+	}
+	processVars(v, func(ref string) string {
+		// Secrets that cannot be resolved are replaced with an empty string like unknown variables.
+		// The workflow resolves a task's secrets before the task runs to report the error.
+		value, _ := ResolveSecret(ref, envFile)
+		return value
+	})
+}
+
+func processVars(v any, secret secretResolver) {
 	val := reflect.ValueOf(v).Elem()
 	for i := 0; i < val.NumField(); i++ {
 		field := val.Field(i)
 		if !field.CanSet() {
 			continue
 		}
-		processField(field)
+		processField(field, secret)
 	}
 }
 
-func processField(field reflect.Value) {
+func processField(field reflect.Value, secret secretResolver) {
 	//goland:noinspection GoSwitchMissingCasesForIotaConsts
 	switch field.Kind() {
 	case reflect.String:
-		field.SetString(replaceVarsInString(field.String()))
+		field.SetString(replaceVarsInString(field.String(), secret))
 	case reflect.Map:
-		processMap(field)
+		processMap(field, secret)
 	case reflect.Slice, reflect.Array:
-		processSlice(field)
+		processSlice(field, secret)
 	case reflect.Struct:
-		ProcessVars(field.Addr().Interface())
+		processVars(field.Addr().Interface(), secret)
 	case reflect.Ptr:
 		if !field.IsNil() && field.Elem().Kind() == reflect.Struct {
-			ProcessVars(field.Elem().Addr().Interface())
+			processVars(field.Elem().Addr().Interface(), secret)
 		}
 	case reflect.Interface:
 		if !field.IsNil() {
 			elem := field.Elem()
 			if elem.Kind() == reflect.String {
-				field.Set(reflect.ValueOf(replaceVarsInString(elem.Interface())))
+				field.Set(reflect.ValueOf(replaceVarsInString(elem.Interface(), secret)))
 			} else {
-				processField(elem)
+				processField(elem, secret)
 			}
 		}
 	}
 }
 
-func processMap(field reflect.Value) {
+func processMap(field reflect.Value, secret secretResolver) {
 	// Check if it's map[string]string or map[string]any
 	if field.Type().Elem().Kind() == reflect.String {
 		original := field.Interface().(map[string]string)
 		newMap := make(map[string]string)
 		for k, v := range original {
-			newMap[k] = replaceVarsInString(v)
+			newMap[k] = replaceVarsInString(v, secret)
 		}
 		field.Set(reflect.ValueOf(newMap))
 		return
@@ -135,23 +225,23 @@ func processMap(field reflect.Value) {
 	for k, v := range original {
 		rv := reflect.ValueOf(v)
 		if rv.Kind() == reflect.Map || rv.Kind() == reflect.Struct {
-			ProcessVars(&v)
+			processVars(&v, secret)
 		} else if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
-			newMap[k] = processSliceValue(rv)
+			newMap[k] = processSliceValue(rv, secret)
 			continue
 		}
-		newMap[k] = replaceVarsInString(v)
+		newMap[k] = replaceVarsInString(v, secret)
 	}
 	field.Set(reflect.ValueOf(newMap))
 }
 
-func processSlice(field reflect.Value) {
+func processSlice(field reflect.Value, secret secretResolver) {
 
 	// Handle slice of strings
 	if field.Type().Elem().Kind() == reflect.String {
 		for i := 0; i < field.Len(); i++ {
 			strValue := field.Index(i).String()
-			field.Index(i).SetString(replaceVarsInString(strValue))
+			field.Index(i).SetString(replaceVarsInString(strValue, secret))
 		}
 		return
 	}
@@ -160,44 +250,46 @@ func processSlice(field reflect.Value) {
 	for i := 0; i < field.Len(); i++ {
 		elem := field.Index(i)
 		if elem.Kind() == reflect.Struct {
-			ProcessVars(elem.Addr().Interface())
+			processVars(elem.Addr().Interface(), secret)
 		} else if elem.Kind() == reflect.Map && elem.Type().Key().Kind() == reflect.String {
 			vm := elem.Interface().(map[string]any)
 			newMap := make(map[string]any)
 			for k, v := range vm {
-				ProcessVars(&v)
-				newMap[k] = replaceVarsInString(v)
+				processVars(&v, secret)
+				newMap[k] = replaceVarsInString(v, secret)
 			}
 			elem.Set(reflect.ValueOf(newMap))
 		}
 	}
 }
 
-func processSliceValue(rv reflect.Value) []any {
+func processSliceValue(rv reflect.Value, secret secretResolver) []any {
 	slice := make([]any, rv.Len())
 	for i := 0; i < rv.Len(); i++ {
 		item := rv.Index(i).Interface()
 		if reflect.TypeOf(item).Kind() == reflect.Map ||
 			reflect.TypeOf(item).Kind() == reflect.Struct {
-			ProcessVars(&item)
+			processVars(&item, secret)
 		}
-		slice[i] = replaceVarsInString(item)
+		slice[i] = replaceVarsInString(item, secret)
 	}
 	return slice
 }
 
-// Utility function to replace \{\{...\}\} placeholders in strings
-func replaceVarsInString(str any) string {
+// Utility function to replace \{\{...\}\} placeholders in strings. Secret references are resolved with
+// secret, or kept if it is nil. Replacements are scanned again, so that a variable may contain placeholders.
+func replaceVarsInString(str any, secret secretResolver) string {
 	s, ok := str.(string)
 	if !ok {
 		return AnyToString(str)
 	}
-	for {
-		start := strings.Index(s, "{{")
-		end := strings.Index(s, "}}")
+	for pos := 0; ; {
+		start := strings.Index(s[pos:], "{{")
+		end := strings.Index(s[pos:], "}}")
 		if start == -1 || end == -1 || start > end {
 			break
 		}
+		start, end = pos+start, pos+end
 		varName := s[start+2 : end]
 		var replacement string
 		switch varName {
@@ -208,11 +300,18 @@ func replaceVarsInString(str any) string {
 		case "epoch":
 			replacement = fmt.Sprintf("%d", time.Now().Unix())
 		default:
-			if resolvedValue, ok := Variables[varName]; ok {
+			if strings.HasPrefix(varName, SecretPrefix) {
+				if secret == nil {
+					pos = end + 2
+					continue
+				}
+				replacement = secret(strings.TrimPrefix(varName, SecretPrefix))
+			} else if resolvedValue, ok := LookupVar(varName); ok {
 				replacement = AnyToString(resolvedValue)
 			}
 		}
 		s = s[:start] + replacement + s[end+2:]
+		pos = start
 	}
 	return s
 }
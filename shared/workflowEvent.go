@@ -0,0 +1,69 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// WorkflowEvent is used to report workflow-level events such as the start and end of a run
+type WorkflowEvent struct {
+	MessageType string         `json:"message_type"`   // Message type
+	RunID       string         `json:"run_id"`         // Unique ID of the workflow run
+	Path        string         `json:"path,omitempty"` // Position of the workflow_run task for events from a child workflow
+	Timestamp   time.Time      `json:"timestamp"`      // Time the event occurred
+	Msg         string         `json:"msg,omitempty"`  // Event message
+	Data        map[string]any `json:"data,omitempty"` // Event data
+}
+
+// serialize is a non-exported function that attempts to serialize the event to a JSON string
+func (we *WorkflowEvent) serialize(prefix, indent string) string {
+	var data []byte
+	var err error
+
+	// MarshalIndent always inserts newlines, so use Marshal for compact output
+	if prefix == "" && indent == "" {
+		data, err = json.Marshal(we)
+	} else {
+		data, err = json.MarshalIndent(we, prefix, indent)
+	}
+	if err != nil {
+		// If serialization fails, return an error event
+		errorEvent := WorkflowEvent{
+			MessageType: we.MessageType,
+			RunID:       we.RunID,
+			Timestamp:   we.Timestamp,
+			Msg:         fmt.Sprintf("error serializing workflow event: %s", err.Error())}
+		data, err = json.Marshal(errorEvent)
+		if err != nil {
+			// If serializing the error event fails, return an empty string
+			data = []byte{}
+		}
+	}
+	return Redact(string(data))
+}
+
+// Serialize the event to a JSON string
+func (we *WorkflowEvent) Serialize() string {
+	return we.serialize("", "")
+}
+
+// SerializePretty serializes the event to a pretty-printed JSON string
+func (we *WorkflowEvent) SerializePretty() string {
+	return we.serialize("", "  ")
+}
+
+// String attempts to return a human-readable string representation of the event
+func (we *WorkflowEvent) String() string {
+	r := fmt.Sprintf("* %s (run %s)", we.MessageType, we.RunID)
+	if we.Path != "" {
+		r = fmt.Sprintf("* %s (run %s, task %s)", we.MessageType, we.RunID, we.Path)
+	}
+	if we.Msg != "" {
+		r += fmt.Sprintf(": %s", we.Msg)
+	}
+	return Redact(r)
+}
@@ -0,0 +1,317 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+// Package signature signs and verifies workflow files with ed25519 keys. A signature is stored next to
+// the workflow file, with a .sig extension, and covers the workflow file and the child workflow files it
+// runs with workflow_run, so that the files executed are the ones that were reviewed.
+package signature
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// Extension is appended to the name of a workflow file to obtain the name of its signature file
+const Extension = ".sig"
+
+// TrustedKeysEnv is the environment variable naming the file of trusted public keys
+const TrustedKeysEnv = "OPSBLADE_TRUSTED_KEYS"
+
+// version is the version of the signature format
+const version = 1
+
+// Signature is the content of a signature file
+type Signature struct {
+	Version   int    `json:"version"`
+	KeyID     string `json:"key_id"`    // ID of the key that made the signature
+	Files     []File `json:"files"`     // The signed file, followed by the files it includes
+	Signature []byte `json:"signature"` // ed25519 signature of the file list
+}
+
+// File is a file covered by a signature
+type File struct {
+	Path   string `json:"path"`   // Path relative to the directory of the signed file
+	SHA256 string `json:"sha256"` // Hash of the canonical file bytes
+}
+
+// Verified describes a verified signature
+type Verified struct {
+	KeyID string            // ID of the key that made the signature
+	Files map[string]string // Hashes of the covered files, by absolute path
+}
+
+// Canonical returns the canonical bytes of a file: without a UTF-8 byte order mark and with LF line endings,
+// so that a signature survives a checkout that converts line endings
+func Canonical(data []byte) []byte {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	return bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+}
+
+// Hash returns the SHA-256 hash of the canonical bytes of a file, hex encoded
+func Hash(data []byte) string {
+	sum := sha256.Sum256(Canonical(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// KeyID returns a short identifier of a public key
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// LoadPrivateKey reads an ed25519 private key from a PEM file, such as one created with
+// openssl genpkey -algorithm ed25519
+//
+//goland:noinspection GoUnusedExportedFunction
+func LoadPrivateKey(filename string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read private key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s is not a PEM private key", filename)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse private key %s: %w", filename, err)
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an ed25519 private key", filename)
+	}
+	return edKey, nil
+}
+
+// LoadPublicKeys reads the trusted ed25519 public keys from a file of one or more PEM public keys, such as
+// ones created with openssl pkey -pubout
+//
+//goland:noinspection GoUnusedExportedFunction
+func LoadPublicKeys(filename string) ([]ed25519.PublicKey, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read trusted keys: %w", err)
+	}
+
+	var keys []ed25519.PublicKey
+	for {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse public key in %s: %w", filename, err)
+		}
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%s contains a public key that is not ed25519", filename)
+		}
+		keys = append(keys, edKey)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s does not contain any PEM public keys", filename)
+	}
+	return keys, nil
+}
+
+// Sign signs a workflow file and the child workflow files it includes
+//
+//goland:noinspection GoUnusedExportedFunction
+func Sign(filename string, key ed25519.PrivateKey) (*Signature, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file: %w", err)
+	}
+	files := []File{{Path: filepath.Base(filename), SHA256: Hash(data)}}
+
+	includes, err := Includes(filename, data)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(filename)
+	for _, include := range includes {
+		includeData, err := os.ReadFile(filepath.Join(dir, include))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read included file: %w", err)
+		}
+		files = append(files, File{Path: filepath.ToSlash(include), SHA256: Hash(includeData)})
+	}
+
+	s := &Signature{Version: version, KeyID: KeyID(key.Public().(ed25519.PublicKey)), Files: files}
+	s.Signature = ed25519.Sign(key, s.message())
+	return s, nil
+}
+
+// Write writes the signature file of a workflow file
+//
+//goland:noinspection GoUnusedExportedFunction
+func (s *Signature) Write(filename string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return shared.WriteFileAtomic(filename+Extension, append(data, '\n'), 0644)
+}
+
+// Verify verifies the signature of a workflow file, whose content was already read, with the trusted keys.
+// It fails if the file is not signed, if the signature was not made with a trusted key, or if the file
+// or any file it includes was modified since it was signed.
+//
+//goland:noinspection GoUnusedExportedFunction
+func Verify(filename string, data []byte, keys []ed25519.PublicKey) (*Verified, error) {
+	sigData, err := os.ReadFile(filename + Extension)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s is not signed", filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read signature: %w", err)
+	}
+	var s Signature
+	if err = json.Unmarshal(sigData, &s); err != nil {
+		return nil, fmt.Errorf("invalid signature file %s: %w", filename+Extension, err)
+	}
+	if s.Version != version {
+		return nil, fmt.Errorf("unsupported signature version %d in %s", s.Version, filename+Extension)
+	}
+	if len(s.Files) == 0 {
+		return nil, fmt.Errorf("signature %s does not cover any files", filename+Extension)
+	}
+
+	i := slices.IndexFunc(keys, func(k ed25519.PublicKey) bool { return KeyID(k) == s.KeyID })
+	if i == -1 {
+		return nil, fmt.Errorf("%s is signed with key %s, which is not trusted", filename, s.KeyID)
+	}
+	if !ed25519.Verify(keys[i], s.message(), s.Signature) {
+		return nil, fmt.Errorf("signature of %s is invalid", filename)
+	}
+	if Hash(data) != s.Files[0].SHA256 {
+		return nil, fmt.Errorf("%s was modified after it was signed", filename)
+	}
+
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	v := &Verified{KeyID: s.KeyID, Files: map[string]string{abs: s.Files[0].SHA256}}
+
+	// Included files are checked now, so that a modified file fails the workflow before anything is run,
+	// and again when they are loaded
+	dir := filepath.Dir(abs)
+	for _, f := range s.Files[1:] {
+		path := filepath.Join(dir, filepath.FromSlash(f.Path))
+		includeData, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read included file: %w", err)
+		}
+		if Hash(includeData) != f.SHA256 {
+			return nil, fmt.Errorf("%s was modified after %s was signed", path, filename)
+		}
+		v.Files[path] = f.SHA256
+	}
+	return v, nil
+}
+
+// message returns the bytes that are signed: the format version and the hash and path of each file
+func (s *Signature) message() []byte {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "opsblade-signature-v%d\n", s.Version)
+	for _, f := range s.Files {
+		_, _ = fmt.Fprintf(&b, "%s %s\n", f.SHA256, f.Path)
+	}
+	return []byte(b.String())
+}
+
+// Includes returns the child workflow files that a workflow file runs with workflow_run, recursively,
+// relative to the directory of the workflow file. Files named with variables cannot be known in advance
+// and are not included, so they must be signed separately.
+//
+//goland:noinspection GoUnusedExportedFunction
+func Includes(filename string, data []byte) ([]string, error) {
+	dir := filepath.Dir(filename)
+	var includes []string
+	seen := map[string]bool{filepath.Clean(filename): true}
+
+	var visit func(file string, data []byte) error
+	visit = func(file string, data []byte) error {
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("unable to parse %s: %w", file, err)
+		}
+		for _, child := range workflowRunFiles(doc) {
+			if !filepath.IsAbs(child) {
+				child = filepath.Join(filepath.Dir(file), child)
+			}
+			child = filepath.Clean(child)
+			if seen[child] {
+				continue
+			}
+			seen[child] = true
+
+			rel, err := filepath.Rel(dir, child)
+			if err != nil {
+				return fmt.Errorf("unable to include %s: %w", child, err)
+			}
+			includes = append(includes, rel)
+
+			childData, err := os.ReadFile(child)
+			if err != nil {
+				return fmt.Errorf("unable to read included file: %w", err)
+			}
+			if err = visit(child, childData); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := visit(filename, data); err != nil {
+		return nil, err
+	}
+	return includes, nil
+}
+
+// workflowRunFiles returns the files of the workflow_run tasks anywhere in a workflow, including macros,
+// handlers, and nested task lists
+func workflowRunFiles(v any) []string {
+	var files []string
+	switch value := v.(type) {
+	case map[string]any:
+		if task, _ := value["task"].(string); task == "workflow_run" {
+			if file, ok := value["file"].(string); ok && file != "" && !strings.Contains(file, "{{") {
+				files = append(files, file)
+			}
+		}
+		// Keys are sorted so that the files are always listed in the same order
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			files = append(files, workflowRunFiles(value[k])...)
+		}
+	case []any:
+		for _, item := range value {
+			files = append(files, workflowRunFiles(item)...)
+		}
+	}
+	return files
+}
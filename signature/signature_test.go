@@ -0,0 +1,169 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package signature
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const parentWorkflow = `name: deploy
+tasks:
+  - name: Run child
+    task: workflow_run
+    file: child.yaml
+`
+
+const childWorkflow = `tasks:
+  - name: Stop
+    task: cmd_exec
+    cmd: "true"
+`
+
+// TestCanonical tests that a byte order mark and CRLF line endings do not change the hash
+func TestCanonical(t *testing.T) {
+	want := Hash([]byte(parentWorkflow))
+	for name, data := range map[string][]byte{
+		"bom":      append([]byte("\xef\xbb\xbf"), parentWorkflow...),
+		"crlf":     []byte(strings.ReplaceAll(parentWorkflow, "\n", "\r\n")),
+		"bom crlf": append([]byte("\xef\xbb\xbf"), strings.ReplaceAll(parentWorkflow, "\n", "\r\n")...),
+	} {
+		if got := Hash(data); got != want {
+			t.Errorf("%s: hash %s, want %s", name, got, want)
+		}
+	}
+	if Hash([]byte(parentWorkflow+" ")) == want {
+		t.Error("a modified file has the same hash")
+	}
+}
+
+// TestSignVerify tests that a signed workflow and the child workflow it runs are verified, and that any
+// modification of them or of the signature, or a key that is not trusted, is refused
+func TestSignVerify(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPublic, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(t *testing.T, dir string) // Changes the files after they are signed
+		keys    []ed25519.PublicKey
+		wantErr string
+	}{
+		{"unmodified", nil, []ed25519.PublicKey{public}, ""},
+		{"one of several keys", nil, []ed25519.PublicKey{otherPublic, public}, ""},
+		{"checked out with CRLF and a BOM", func(t *testing.T, dir string) {
+			write(t, dir, "workflow.yaml", "\xef\xbb\xbf"+strings.ReplaceAll(parentWorkflow, "\n", "\r\n"))
+			write(t, dir, "child.yaml", strings.ReplaceAll(childWorkflow, "\n", "\r\n"))
+		}, []ed25519.PublicKey{public}, ""},
+		{"untrusted key", nil, []ed25519.PublicKey{otherPublic}, "not trusted"},
+		{"modified workflow", func(t *testing.T, dir string) {
+			write(t, dir, "workflow.yaml", strings.Replace(parentWorkflow, "deploy", "destroy", 1))
+		}, []ed25519.PublicKey{public}, "modified"},
+		{"modified child workflow", func(t *testing.T, dir string) {
+			write(t, dir, "child.yaml", strings.Replace(childWorkflow, `"true"`, "rm", 1))
+		}, []ed25519.PublicKey{public}, "child.yaml was modified"},
+		{"missing child workflow", func(t *testing.T, dir string) {
+			if err := os.Remove(filepath.Join(dir, "child.yaml")); err != nil {
+				t.Fatal(err)
+			}
+		}, []ed25519.PublicKey{public}, "unable to read included file"},
+		{"modified signature", func(t *testing.T, dir string) {
+			var s Signature
+			readSignature(t, dir, &s)
+			s.Signature[0] ^= 0xff
+			writeSignature(t, dir, &s)
+		}, []ed25519.PublicKey{public}, "invalid"},
+		{"hash replaced in signature", func(t *testing.T, dir string) {
+			var s Signature
+			readSignature(t, dir, &s)
+			s.Files[1].SHA256 = Hash([]byte("rm -rf /"))
+			writeSignature(t, dir, &s)
+		}, []ed25519.PublicKey{public}, "invalid"},
+		{"not signed", func(t *testing.T, dir string) {
+			if err := os.Remove(filepath.Join(dir, "workflow.yaml"+Extension)); err != nil {
+				t.Fatal(err)
+			}
+		}, []ed25519.PublicKey{public}, "not signed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			filename := write(t, dir, "workflow.yaml", parentWorkflow)
+			write(t, dir, "child.yaml", childWorkflow)
+
+			s, err := Sign(filename, private)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(s.Files) != 2 || s.Files[1].Path != "child.yaml" {
+				t.Fatalf("the signature does not cover the child workflow: %+v", s.Files)
+			}
+			if err = s.Write(filename); err != nil {
+				t.Fatal(err)
+			}
+			if tt.modify != nil {
+				tt.modify(t, dir)
+			}
+
+			data, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err := Verify(filename, data, tt.keys)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.KeyID != KeyID(public) || len(v.Files) != 2 {
+				t.Errorf("unexpected verification: %+v", v)
+			}
+			if _, ok := v.Files[filepath.Join(dir, "child.yaml")]; !ok {
+				t.Errorf("the child workflow is not verified: %+v", v.Files)
+			}
+		})
+	}
+}
+
+// write writes a file in dir and returns its name
+func write(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	filename := filepath.Join(dir, name)
+	if err := os.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func readSignature(t *testing.T, dir string, s *Signature) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "workflow.yaml"+Extension))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(data, s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeSignature(t *testing.T, dir string, s *Signature) {
+	t.Helper()
+	if err := s.Write(filepath.Join(dir, "workflow.yaml")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package window
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule is a parsed cron expression. Each field is a set of allowed values.
+type schedule struct {
+	expr   string
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	anyDom bool // The day of the month is *
+	anyDow bool // The day of the week is *
+}
+
+// field describes the range and names of a cron field
+type field struct {
+	name  string
+	min   int
+	max   int
+	names []string // Names of the values, starting at min
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12,
+		names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}}
+	dowField = field{name: "day of week", min: 0, max: 7,
+		names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}}
+)
+
+// parseSchedule parses a cron expression of five fields: minute, hour, day of month, month, and day of
+// week. Fields are *, values, ranges (a-b), steps (*/n or a-b/n), or comma-separated lists of these.
+// Months and days of the week may be names, and Sunday is 0 or 7. As in cron, if both the day of the
+// month and the day of the week are restricted, a day matching either is allowed.
+func parseSchedule(expr string) (*schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%q must have 5 fields: minute hour day-of-month month day-of-week", expr)
+	}
+
+	s := &schedule{expr: expr, anyDom: fields[2] == "*", anyDow: fields[4] == "*"}
+	var err error
+	for i, f := range []struct {
+		set *uint64
+		def field
+	}{{&s.minute, minuteField}, {&s.hour, hourField}, {&s.dom, domField}, {&s.month, monthField}, {&s.dow, dowField}} {
+		if *f.set, err = parseField(fields[i], f.def); err != nil {
+			return nil, fmt.Errorf("%q: %w", expr, err)
+		}
+	}
+
+	// Sunday may be written as 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+// parseField parses one field of a cron expression into a set of values
+func parseField(spec string, def field) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(spec, ",") {
+		rangeSpec, stepSpec, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepSpec); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s", stepSpec, def.name)
+			}
+		}
+
+		low, high := def.min, def.max
+		if rangeSpec != "*" {
+			lowSpec, highSpec, isRange := strings.Cut(rangeSpec, "-")
+			var err error
+			if low, err = def.value(lowSpec); err != nil {
+				return 0, err
+			}
+			high = low
+			if isRange {
+				if high, err = def.value(highSpec); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				high = def.max // a/n is a, a+n, ... up to the maximum
+			}
+			if high < low {
+				return 0, fmt.Errorf("invalid range %q in %s", rangeSpec, def.name)
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// value parses a value of a field, which may be a name
+func (f field) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s %q", f.name, s)
+	}
+	return v, nil
+}
+
+// matches returns true if the minute of t is allowed by the schedule
+func (s *schedule) matches(t time.Time) bool {
+	if s.minute&(1<<t.Minute()) == 0 || s.hour&(1<<t.Hour()) == 0 || s.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	if s.anyDom || s.anyDow {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,292 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+// Package window restricts when tasks may change something to maintenance windows, described by cron-like
+// expressions in a time zone, outside of change-freeze periods loaded from a file. Time is read from an
+// injectable clock, so that gates can be tested at any time.
+package window
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	// Time zones are embedded so that they do not depend on the host
+	_ "time/tzdata"
+)
+
+// horizon is how far ahead the next opening of a window is searched for
+const horizon = 366 * 24 * time.Hour
+
+// Clock provides the current time and waits. It is replaced in tests.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration, cancel <-chan struct{}) bool // Returns false if cancelled
+}
+
+// SystemClock is the clock of the host
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Sleep(d time.Duration, cancel <-chan struct{}) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-cancel:
+		return false
+	}
+}
+
+// Window contains the window settings of a workflow or task
+type Window struct {
+	Timezone   string   `yaml:"timezone" json:"timezone"`       // Time zone of allow and freeze dates, local by default
+	Allow      []string `yaml:"allow" json:"allow"`             // Cron-like expressions of the minutes changes are allowed
+	FreezeFile string   `yaml:"freeze_file" json:"freeze_file"` // File of change-freeze periods
+	Wait       int      `yaml:"wait" json:"wait"`               // Seconds to wait for the window to open, 0 fails immediately
+}
+
+// Freeze is a period in which no changes are allowed
+type Freeze struct {
+	Name   string    `yaml:"name"`
+	Reason string    `yaml:"reason"`
+	Start  time.Time `yaml:"-"`
+	End    time.Time `yaml:"-"` // Exclusive
+}
+
+// freezeFile is the content of a freeze file. Dates are RFC 3339 times, or dates and times without a time
+// zone, which are in the time zone of the window. An end date without a time includes that day.
+type freezeFile struct {
+	Freezes []struct {
+		Name   string `yaml:"name"`
+		Reason string `yaml:"reason"`
+		Start  string `yaml:"start"`
+		End    string `yaml:"end"`
+	} `yaml:"freezes"`
+}
+
+// Gate is a compiled window
+type Gate struct {
+	loc     *time.Location
+	allow   []*schedule
+	freezes []Freeze
+	wait    time.Duration
+}
+
+// Closed is the error returned when a gate is closed
+type Closed struct {
+	Reason string    // Why the gate is closed
+	Next   time.Time // When the gate opens next, zero if not within a year
+}
+
+func (c *Closed) Error() string {
+	if c.Next.IsZero() {
+		return fmt.Sprintf("%s, and no window opens within a year", c.Reason)
+	}
+	return fmt.Sprintf("%s, the next window opens at %s", c.Reason, c.Next.Format(time.RFC3339))
+}
+
+// Compile parses the window settings. A relative freeze file is relative to dir.
+//
+//goland:noinspection GoUnusedExportedFunction
+func (w *Window) Compile(dir string) (*Gate, error) {
+	g := &Gate{loc: time.Local, wait: time.Duration(w.Wait) * time.Second}
+	if w.Wait < 0 {
+		return nil, fmt.Errorf("window wait must not be negative")
+	}
+	if w.Timezone != "" {
+		var err error
+		if g.loc, err = time.LoadLocation(w.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid window timezone: %w", err)
+		}
+	}
+	for _, expr := range w.Allow {
+		s, err := parseSchedule(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid window: %w", err)
+		}
+		g.allow = append(g.allow, s)
+	}
+	if w.FreezeFile != "" {
+		file := w.FreezeFile
+		if !filepath.IsAbs(file) && dir != "" {
+			file = filepath.Join(dir, file)
+		}
+		var err error
+		if g.freezes, err = loadFreezes(file, g.loc); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// loadFreezes reads the freeze periods of a freeze file
+func loadFreezes(filename string, loc *time.Location) ([]Freeze, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read freeze file: %w", err)
+	}
+	var ff freezeFile
+	if err = yaml.Unmarshal(data, &ff); err != nil {
+		return nil, fmt.Errorf("freeze file deserialization error: %w", err)
+	}
+
+	freezes := make([]Freeze, 0, len(ff.Freezes))
+	for i, f := range ff.Freezes {
+		name := f.Name
+		if name == "" {
+			name = fmt.Sprintf("freeze %d", i+1)
+		}
+		start, _, err := parseTime(f.Start, loc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: invalid start: %w", filename, name, err)
+		}
+		end, dateOnly, err := parseTime(f.End, loc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: invalid end: %w", filename, name, err)
+		}
+		if dateOnly {
+			end = end.AddDate(0, 0, 1)
+		}
+		if !end.After(start) {
+			return nil, fmt.Errorf("%s: %s: end is not after start", filename, name)
+		}
+		freezes = append(freezes, Freeze{Name: name, Reason: f.Reason, Start: start, End: end})
+	}
+	return freezes, nil
+}
+
+// parseTime parses a freeze date. It returns true if the date has no time.
+func parseTime(s string, loc *time.Location) (time.Time, bool, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, false, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02T15:04:05"} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, false, nil
+		}
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, loc)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%q is not a date", s)
+	}
+	return t, true, nil
+}
+
+// Check returns nil if changes are allowed at t, or a *Closed error
+func (g *Gate) Check(t time.Time) error {
+	reason := g.closed(t)
+	if reason == "" {
+		return nil
+	}
+	return &Closed{Reason: reason, Next: g.next(t)}
+}
+
+// closed returns why the gate is closed at t, or an empty string if it is open
+func (g *Gate) closed(t time.Time) string {
+	t = t.In(g.loc)
+	if f := g.freeze(t); f != nil {
+		until := f.End.In(g.loc).Format(time.RFC3339)
+		if f.Reason != "" {
+			return fmt.Sprintf("change freeze %s is in effect until %s (%s)", f.Name, until, f.Reason)
+		}
+		return fmt.Sprintf("change freeze %s is in effect until %s", f.Name, until)
+	}
+	if g.allowed(t) {
+		return ""
+	}
+	exprs := make([]string, 0, len(g.allow))
+	for _, s := range g.allow {
+		exprs = append(exprs, s.expr)
+	}
+	return fmt.Sprintf("%s is outside the maintenance windows %s", t.Format("Mon 2006-01-02 15:04 MST"), strings.Join(exprs, "; "))
+}
+
+// freeze returns the freeze period in effect at t, if any
+func (g *Gate) freeze(t time.Time) *Freeze {
+	for i, f := range g.freezes {
+		if !t.Before(f.Start) && t.Before(f.End) {
+			return &g.freezes[i]
+		}
+	}
+	return nil
+}
+
+// allowed returns true if t is in one of the allowed windows, or if there are none
+func (g *Gate) allowed(t time.Time) bool {
+	if len(g.allow) == 0 {
+		return true
+	}
+	t = t.In(g.loc)
+	for _, s := range g.allow {
+		if s.matches(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// next returns the first minute after t at which the gate is open, or zero if it does not open within
+// a year
+func (g *Gate) next(t time.Time) time.Time {
+	end := t.Add(horizon)
+	for c := t.Truncate(time.Minute).Add(time.Minute); c.Before(end); {
+		// Skip to the end of a freeze rather than checking each minute of it
+		if f := g.freeze(c); f != nil {
+			c = f.End
+			continue
+		}
+		if g.allowed(c) {
+			return c
+		}
+		c = c.Add(time.Minute)
+	}
+	return time.Time{}
+}
+
+// Wait returns nil when changes are allowed. If the gate is closed, it waits for it to open if it opens
+// within the configured wait, calling waiting once with the reason, and otherwise returns a *Closed error.
+// It also returns the error if cancel is closed while waiting.
+//
+//goland:noinspection GoUnusedExportedFunction
+func (g *Gate) Wait(clock Clock, cancel <-chan struct{}, waiting func(*Closed)) error {
+	now := clock.Now()
+	err := g.Check(now)
+	closed, ok := err.(*Closed)
+	if !ok {
+		return err
+	}
+	deadline := now.Add(g.wait)
+	if closed.Next.IsZero() || closed.Next.After(deadline) {
+		return closed
+	}
+
+	if waiting != nil {
+		waiting(closed)
+	}
+	for {
+		// The gate is checked again after sleeping, as the clock may have changed
+		if !clock.Sleep(closed.Next.Sub(now), cancel) {
+			return closed
+		}
+		now = clock.Now()
+		if err = g.Check(now); err == nil {
+			return nil
+		}
+		if closed, ok = err.(*Closed); !ok {
+			return err
+		}
+		if closed.Next.IsZero() || closed.Next.After(deadline) {
+			return closed
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package window
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock that only moves when it sleeps
+type fakeClock struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration, cancel <-chan struct{}) bool {
+	select {
+	case <-cancel:
+		return false
+	default:
+	}
+	c.now = c.now.Add(d)
+	c.slept += d
+	return true
+}
+
+// toronto returns a time in the America/Toronto time zone
+func toronto(t *testing.T, s string) time.Time {
+	loc, err := time.LoadLocation("America/Toronto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tm
+}
+
+// compile compiles a window or fails the test
+func compile(t *testing.T, w Window) *Gate {
+	g, err := w.Compile(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// TestSchedule tests that cron expressions match the expected minutes
+func TestSchedule(t *testing.T) {
+	tests := []struct {
+		expr  string
+		time  string // Time in UTC
+		match bool
+	}{
+		{"* * * * *", "2026-10-20 03:17", true},
+		{"* 18-21 * * 2,4", "2026-10-20 18:00", true},  // Tuesday
+		{"* 18-21 * * 2,4", "2026-10-20 21:59", true},  // Tuesday
+		{"* 18-21 * * 2,4", "2026-10-20 22:00", false}, // Tuesday, after the window
+		{"* 18-21 * * 2,4", "2026-10-21 19:00", false}, // Wednesday
+		{"* 18-21 * * tue,thu", "2026-10-22 19:00", true},
+		{"*/15 * * * *", "2026-10-20 10:45", true},
+		{"*/15 * * * *", "2026-10-20 10:46", false},
+		{"30 2 * * 7", "2026-10-18 02:30", true}, // Sunday as 7
+		{"0 0 1 jan-mar *", "2026-02-01 00:00", true},
+		{"0 0 1 jan-mar *", "2026-04-01 00:00", false},
+		{"0 0 1 * mon", "2026-10-01 00:00", true},  // The first of the month, a Thursday
+		{"0 0 1 * mon", "2026-10-05 00:00", true},  // A Monday
+		{"0 0 1 * mon", "2026-10-06 00:00", false}, // Neither
+		{"5/20 * * * *", "2026-10-20 10:25", true},
+	}
+	for _, tt := range tests {
+		s, err := parseSchedule(tt.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.expr, err)
+		}
+		tm, err := time.Parse("2006-01-02 15:04", tt.time)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.matches(tm); got != tt.match {
+			t.Errorf("%s at %s: got %t, want %t", tt.expr, tt.time, got, tt.match)
+		}
+	}
+}
+
+// TestScheduleInvalid tests that invalid cron expressions are refused
+func TestScheduleInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *",
+		"* * * * 8", "5-1 * * * *", "*/0 * * * *", "* * * * funday", "a * * * *"} {
+		if _, err := parseSchedule(expr); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
+
+// TestGateCheck tests that a gate is open in its windows, in its time zone, and reports when it opens next
+func TestGateCheck(t *testing.T) {
+	g := compile(t, Window{Timezone: "America/Toronto", Allow: []string{"* 18-21 * * tue,thu"}})
+
+	if err := g.Check(toronto(t, "2026-10-20 19:30")); err != nil {
+		t.Errorf("Tuesday evening: %v", err)
+	}
+
+	// 19:30 in Toronto is 23:30 UTC, so the window is in the time zone of the gate
+	if err := g.Check(toronto(t, "2026-10-20 19:30").UTC()); err != nil {
+		t.Errorf("Tuesday evening in UTC: %v", err)
+	}
+
+	err := g.Check(toronto(t, "2026-10-21 10:00"))
+	var closed *Closed
+	if !errors.As(err, &closed) {
+		t.Fatalf("Wednesday morning: expected *Closed, got %v", err)
+	}
+	if want := toronto(t, "2026-10-22 18:00"); !closed.Next.Equal(want) {
+		t.Errorf("next window: got %s, want %s", closed.Next, want)
+	}
+	if !strings.Contains(closed.Error(), "outside the maintenance windows") {
+		t.Errorf("unexpected error: %v", closed)
+	}
+
+	// A gate without windows is always open
+	if err = compile(t, Window{}).Check(toronto(t, "2026-10-21 10:00")); err != nil {
+		t.Errorf("no windows: %v", err)
+	}
+}
+
+// TestFreeze tests that freeze periods close a gate, including the whole last day of a freeze without a time
+func TestFreeze(t *testing.T) {
+	dir := t.TempDir()
+	freezes := `freezes:
+  - name: year-end
+    reason: holiday freeze
+    start: 2026-12-18
+    end: 2027-01-04
+  - name: launch
+    start: 2026-11-03T12:00:00-05:00
+    end: 2026-11-03 20:00
+`
+	if err := os.WriteFile(filepath.Join(dir, "freezes.yaml"), []byte(freezes), 0600); err != nil {
+		t.Fatal(err)
+	}
+	g, err := (&Window{Timezone: "America/Toronto", Allow: []string{"* 18-21 * * tue,thu"}, FreezeFile: "freezes.yaml"}).Compile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = g.Check(toronto(t, "2026-12-22 19:00"))
+	var closed *Closed
+	if !errors.As(err, &closed) {
+		t.Fatalf("during the year-end freeze: expected *Closed, got %v", err)
+	}
+	if !strings.Contains(closed.Reason, "year-end") || !strings.Contains(closed.Reason, "holiday freeze") {
+		t.Errorf("unexpected reason: %s", closed.Reason)
+	}
+	if want := toronto(t, "2027-01-05 18:00"); !closed.Next.Equal(want) {
+		t.Errorf("next window: got %s, want %s", closed.Next, want)
+	}
+
+	// The freeze ends at the end of its last day, a Monday, so the first window is Tuesday evening
+	if err = g.Check(toronto(t, "2027-01-05 18:00")); err != nil {
+		t.Errorf("after the freeze: %v", err)
+	}
+
+	if err = g.Check(toronto(t, "2026-11-03 19:59")); err == nil {
+		t.Error("during the launch freeze: expected an error")
+	}
+	if err = g.Check(toronto(t, "2026-11-03 20:00")); err != nil {
+		t.Errorf("after the launch freeze: %v", err)
+	}
+}
+
+// TestFreezeInvalid tests that invalid freeze files are refused
+func TestFreezeInvalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"date.yaml":  "freezes:\n  - start: tomorrow\n    end: 2026-12-01\n",
+		"order.yaml": "freezes:\n  - start: 2026-12-02\n    end: 2026-12-01\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := (&Window{FreezeFile: name}).Compile(dir); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := (&Window{FreezeFile: "missing.yaml"}).Compile(dir); err == nil {
+		t.Error("missing freeze file: expected an error")
+	}
+	if _, err := (&Window{Timezone: "Mars/Olympus"}).Compile(dir); err == nil {
+		t.Error("invalid time zone: expected an error")
+	}
+}
+
+// TestWait tests that a gate waits for a window that opens within its wait, and fails otherwise
+func TestWait(t *testing.T) {
+	// The window opens 30 minutes later
+	clock := &fakeClock{now: toronto(t, "2026-10-20 17:30")}
+	g := compile(t, Window{Timezone: "America/Toronto", Allow: []string{"* 18-21 * * tue,thu"}, Wait: 3600})
+	waited := 0
+	if err := g.Wait(clock, nil, func(*Closed) { waited++ }); err != nil {
+		t.Fatalf("expected to wait for the window: %v", err)
+	}
+	if waited != 1 || clock.slept != 30*time.Minute {
+		t.Errorf("waiting called %d times, slept %s", waited, clock.slept)
+	}
+
+	// The window opens later than the wait allows, so it fails without waiting
+	clock = &fakeClock{now: toronto(t, "2026-10-20 16:30")}
+	var closed *Closed
+	if err := g.Wait(clock, nil, nil); !errors.As(err, &closed) {
+		t.Fatalf("expected *Closed, got %v", err)
+	}
+	if clock.slept != 0 {
+		t.Errorf("slept %s", clock.slept)
+	}
+
+	// Cancelling stops waiting
+	clock = &fakeClock{now: toronto(t, "2026-10-20 17:30")}
+	cancel := make(chan struct{})
+	close(cancel)
+	if err := g.Wait(clock, cancel, nil); err == nil {
+		t.Error("expected an error when cancelled")
+	}
+
+	// An open gate does not wait
+	clock = &fakeClock{now: toronto(t, "2026-10-20 18:30")}
+	if err := g.Wait(clock, nil, nil); err != nil || clock.slept != 0 {
+		t.Errorf("open gate: %v, slept %s", err, clock.slept)
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// TaskAwait is an engine built-in task that waits for background tasks to finish
+const TaskAwait = "await"
+
+// cancelGrace is how long a cancelled background task is given to stop before an async_wait event reports
+// that the workflow is waiting for it. It is a variable so that tests can shorten it.
+var cancelGrace = 10 * time.Second
+
+// asyncTask is a task running in the background
+type asyncTask struct {
+	id       string
+	rawTask  map[string]any
+	pos      position
+	sequence int
+	start    time.Time
+	cancel   chan struct{}
+	done     chan shared.TaskResult
+}
+
+// awaitSpec contains the settings of an await task
+type awaitSpec struct {
+	IDs []string `json:"ids"` // Background tasks to wait for (all if empty)
+}
+
+// isAsync returns true if a task is to be run in the background
+func isAsync(rawTask map[string]any) bool {
+	async, _ := rawTask["async"].(bool)
+	return async
+}
+
+// asyncID returns the ID used to await a background task: its id field, or its name if there is no id
+func asyncID(rawTask map[string]any) string {
+	if id, ok := rawTask["id"].(string); ok && id != "" {
+		return id
+	}
+	name, _ := rawTask["name"].(string)
+	return name
+}
+
+// validateAsync checks that background tasks can be run in the background and have unique IDs, and that
+// await tasks only refer to background tasks
+func (w *Workflow) validateAsync() error {
+	ids := make(map[string]bool)
+	for _, list := range w.lists() {
+		for i, rawTask := range list.tasks {
+			if !isAsync(rawTask) {
+				continue
+			}
+			taskType, _ := rawTask["task"].(string)
+			if w.builtin(taskType) != nil {
+				return fmt.Errorf("%s %d: %s cannot be run in the background", list.name, i+1, taskType)
+			}
+			id := asyncID(rawTask)
+			if id == "" {
+				return fmt.Errorf("%s %d: a background task requires an id or a name", list.name, i+1)
+			}
+			if ids[id] {
+				return fmt.Errorf("%s %d: duplicate background task id: %s", list.name, i+1, id)
+			}
+			ids[id] = true
+		}
+	}
+
+	for _, list := range w.lists() {
+		for i, rawTask := range list.tasks {
+			if taskType, _ := rawTask["task"].(string); taskType != TaskAwait {
+				continue
+			}
+			for _, id := range awaitIDs(rawTask) {
+				if !ids[id] {
+					return fmt.Errorf("%s %d: await refers to unknown background task: %s", list.name, i+1, id)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// awaitIDs returns the IDs listed in an await task
+func awaitIDs(rawTask map[string]any) []string {
+	list, _ := rawTask["ids"].([]any)
+	ids := make([]string, 0, len(list))
+	for _, item := range list {
+		if id, ok := item.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// startAsync starts a task in the background. If the task cannot be started (or is skipped), its result is
+// reported immediately. It returns true if the workflow should continue.
+func (w *Workflow) startAsync(pos position, sequence int, rawTask map[string]any) bool {
+	start := time.Now()
+	id := asyncID(rawTask)
+
+	// The same background task may be reached again (e.g. in a macro) before it is awaited
+	if slices.ContainsFunc(w.pending, func(job *asyncTask) bool { return job.id == id }) {
+		return w.taskEnd(rawTask, placeResult(rawResult(rawTask, sequence,
+			fmt.Sprintf("background task %s is already running", id)), pos, start))
+	}
+
+	cancel := make(chan struct{})
+	run, result := w.prepareTask(pos, sequence, rawTask, cancel)
+	if run == nil {
+		return w.taskEnd(rawTask, placeResult(result, pos, start))
+	}
+
+	job := &asyncTask{
+		id:       id,
+		rawTask:  rawTask,
+		pos:      pos,
+		sequence: sequence,
+		start:    start,
+		cancel:   cancel,
+		done:     make(chan shared.TaskResult, 1),
+	}
+	w.pending = append(w.pending, job)
+
+	go func() {
+		job.done <- run()
+	}()
+	return true
+}
+
+// takePending removes the background tasks with the specified IDs from the pending list and returns them.
+// If no IDs are specified, all pending background tasks are returned.
+func (w *Workflow) takePending(ids []string) ([]*asyncTask, error) {
+	if len(ids) == 0 {
+		jobs := w.pending
+		w.pending = nil
+		return jobs, nil
+	}
+
+	var jobs []*asyncTask
+	for _, id := range ids {
+		i := slices.IndexFunc(w.pending, func(job *asyncTask) bool { return job.id == id })
+		if i < 0 {
+			return nil, fmt.Errorf("background task %s is not running", id)
+		}
+		jobs = append(jobs, w.pending[i])
+		w.pending = slices.Delete(w.pending, i, i+1)
+	}
+	return jobs, nil
+}
+
+// awaitJobs waits for background tasks to finish and reports their results in order. It returns the
+// results after evaluation and the IDs of the tasks that failed.
+func (w *Workflow) awaitJobs(jobs []*asyncTask) ([]shared.TaskResult, []string) {
+	var results []shared.TaskResult
+	var failed []string
+	for _, job := range jobs {
+		result := placeResult(finishTask(<-job.done), job.pos, job.start)
+		first := len(w.results)
+		if !w.taskEnd(job.rawTask, result) {
+			failed = append(failed, job.id)
+		}
+		results = append(results, w.results[first:]...)
+	}
+	return results, failed
+}
+
+// awaitTask implements the await built-in task. It waits for the listed background tasks (or all of them)
+// and returns their merged data.
+func (w *Workflow) awaitTask(_ position, _ map[string]any, taskContext shared.TaskContext) shared.TaskResult {
+	var spec awaitSpec
+	if err := json.Unmarshal(taskContext.Instructions, &spec); err != nil {
+		return taskContext.Error("failed to deserialize await task", err)
+	}
+
+	jobs, err := w.takePending(spec.IDs)
+	if err != nil {
+		return taskContext.Error("unable to await", err)
+	}
+
+	results, failed := w.awaitJobs(jobs)
+
+	data := make(map[string]any)
+	changed := false
+	for _, r := range results {
+		if r.Success {
+			maps.Copy(data, r.Data)
+		}
+		changed = changed || r.Changed
+	}
+
+	var result shared.TaskResult
+	if len(failed) > 0 {
+		result = taskContext.Error(fmt.Sprintf("background task(s) failed: %s", strings.Join(failed, ", ")), nil)
+		result.Data = data
+	} else {
+		result = taskContext.Result(true, fmt.Sprintf("%d background task(s) completed", len(jobs)), data)
+	}
+	result.Changed = changed
+
+	// The background tasks have already set their variables
+	result.NoVars = true
+	return result
+}
+
+// awaitPending waits for any background tasks that were not awaited. It returns false if one of them failed.
+func (w *Workflow) awaitPending() bool {
+	jobs, _ := w.takePending(nil)
+	_, failed := w.awaitJobs(jobs)
+	return len(failed) == 0
+}
+
+// cancelPending cancels any background tasks that were not awaited and waits for them to stop. Most tasks
+// cannot be interrupted and may still be making changes, so a task that does not stop within the grace
+// period is waited for until it finishes, and the workflow reports that it is waiting with an async_wait
+// event. The tasks are reported with their own results, marked as cancelled.
+func (w *Workflow) cancelPending() {
+	jobs, _ := w.takePending(nil)
+	for _, job := range jobs {
+		close(job.cancel)
+	}
+
+	deadline := time.Now().Add(cancelGrace)
+	for _, job := range jobs {
+		var result shared.TaskResult
+		select {
+		case result = <-job.done:
+		case <-time.After(time.Until(deadline)):
+			label := shared.TaskResult{Phase: job.pos.phase, Parent: job.pos.parent, Sequence: job.sequence}
+			w.progress(shared.WorkflowEvent{
+				MessageType: "async_wait",
+				Msg:         fmt.Sprintf("waiting for cancelled background task %s [%s] to finish", job.id, label.Step()),
+				Data:        map[string]any{"id": job.id, "step": label.Step()},
+			})
+			result = <-job.done
+		}
+		result = finishTask(result)
+		result.Cancelled = true
+		w.taskStop(placeResult(result, job.pos, job.start))
+	}
+}
+
+// rawResult returns a failed result for a task that could not be run
+func rawResult(rawTask map[string]any, sequence int, msg string) shared.TaskResult {
+	name, _ := rawTask["name"].(string)
+	taskType, _ := rawTask["task"].(string)
+	return shared.TaskResult{
+		MessageType: "task_stop",
+		Success:     false,
+		Msg:         msg,
+		Sequence:    sequence,
+		Name:        name,
+		Task:        taskType,
+	}
+}
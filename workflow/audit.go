@@ -0,0 +1,73 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package workflow
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/OpsBlade/OpsBlade/audit"
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// openAudit opens the audit log set by the caller or the workflow file, if any. A child workflow uses the
+// audit log of its parent, so it is only opened if the parent has none.
+func (w *Workflow) openAudit() error {
+	if w.audit != nil {
+		return nil
+	}
+
+	filename := w.auditFile
+	if filename == "" && w.AuditLog != "" {
+		filename = w.AuditLog
+		if !filepath.IsAbs(filename) && w.source != "" {
+			filename = filepath.Join(filepath.Dir(w.source), filename)
+		}
+	}
+	if filename == "" {
+		return nil
+	}
+
+	var err error
+	w.audit, err = audit.Open(filename)
+	return err
+}
+
+// auditTask records a mutating task in the audit log. If the entry cannot be written the task fails, so
+// that the workflow does not continue to make changes that are not recorded.
+func (w *Workflow) auditTask(pos position, taskContext shared.TaskContext, parameters map[string]any, start time.Time,
+	result shared.TaskResult) shared.TaskResult {
+	workflowFile := w.source
+	if workflowFile != "" {
+		if abs, err := filepath.Abs(workflowFile); err == nil {
+			workflowFile = abs
+		}
+	}
+
+	label := shared.TaskResult{Phase: pos.phase, Parent: pos.parent, Sequence: taskContext.Sequence}
+	err := w.audit.Append(audit.Entry{
+		Time:       time.Now().UTC(),
+		StartTime:  start.UTC(),
+		RunID:      w.runID,
+		Workflow:   workflowFile,
+		Step:       label.Step(),
+		Name:       taskContext.Name,
+		Task:       taskContext.Task,
+		DryRun:     taskContext.DryRun,
+		Plan:       w.planMode,
+		User:       shared.CurrentUser(),
+		AWS:        result.AWSIdentity,
+		JiraUser:   result.JiraUser,
+		Parameters: parameters,
+		Success:    result.Success,
+		Changed:    result.Changed,
+		Msg:        shared.Redact(result.Msg),
+	})
+	if err != nil {
+		result.Success = false
+		result.Msg = fmt.Sprintf("%s\n\nUnable to write audit log: %v", result.Msg, err)
+	}
+	return result
+}
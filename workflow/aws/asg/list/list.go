@@ -49,7 +49,8 @@ func (t *Task) Execute() shared.TaskResult {
 	amazonInstance, err := cloudaws.New(
 		cloudaws.WithRegion(t.Region),
 		cloudaws.WithEnvironment(envFile),
-		cloudaws.WithProfile(t.Profile))
+		cloudaws.WithProfile(t.Profile),
+		cloudaws.WithTaskContext(&t.Context))
 	if err != nil || amazonInstance == nil {
 		return t.Context.Error("failed to create AWS client", err)
 	}
@@ -32,13 +32,14 @@ type Task struct {
 func init() {
 	shared.RegisterTask("aws_asg_refresh", func(context shared.TaskContext) shared.Task {
 		return &Task{Context: context}
-	})
+	}, shared.Mutating())
 }
 
-func (t *Task) Execute() shared.TaskResult {
-
+// prepare deserializes the task, creates the AWS client, and finds the autoscaling groups to refresh.
+// If it fails, the client is nil and the error result is returned.
+func (t *Task) prepare() (*autoscaling.Client, []string, bool, shared.TaskResult) {
 	if err := json.Unmarshal(t.Context.Instructions, t); err != nil {
-		return t.Context.Error("failed to deserialize data", err)
+		return nil, nil, false, t.Context.Error("failed to deserialize data", err)
 	}
 
 	// Resolve input variables
@@ -49,7 +50,7 @@ func (t *Task) Execute() shared.TaskResult {
 	}
 
 	if len(t.LaunchTemplates) < 1 {
-		return t.Context.Error("at least one launch template must be specified", nil)
+		return nil, nil, false, t.Context.Error("at least one launch template must be specified", nil)
 	}
 
 	// SkipMatching defaults to true
@@ -59,7 +60,7 @@ func (t *Task) Execute() shared.TaskResult {
 		if err == nil {
 			skipMatching = value
 		} else {
-			return t.Context.Error("failed to parse skip_matching", err)
+			return nil, nil, false, t.Context.Error("failed to parse skip_matching", err)
 		}
 	}
 
@@ -69,9 +70,10 @@ func (t *Task) Execute() shared.TaskResult {
 	amazonInstance, err := cloudaws.New(
 		cloudaws.WithRegion(t.Region),
 		cloudaws.WithEnvironment(envFile),
-		cloudaws.WithProfile(t.Profile))
+		cloudaws.WithProfile(t.Profile),
+		cloudaws.WithTaskContext(&t.Context))
 	if err != nil || amazonInstance == nil {
-		return t.Context.Error("failed to create AWS client", err)
+		return nil, nil, false, t.Context.Error("failed to create AWS client", err)
 	}
 
 	asgClient := amazonInstance.ASGClient()
@@ -93,7 +95,7 @@ func (t *Task) Execute() shared.TaskResult {
 	for paginator.HasMorePages() {
 		page, err := paginator.NextPage(context.TODO())
 		if err != nil {
-			return t.Context.Error("error describing autoscaling groups", err)
+			return nil, nil, false, t.Context.Error("error describing autoscaling groups", err)
 		}
 
 		var selected bool
@@ -135,7 +137,7 @@ func (t *Task) Execute() shared.TaskResult {
 			if len(t.Select) > 0 {
 				selected, err = shared.ApplySelectionCriteria(asg, t.Select)
 				if err != nil {
-					return t.Context.Error("failed applying selection criteria", err)
+					return nil, nil, false, t.Context.Error("failed applying selection criteria", err)
 				}
 
 				if selected {
@@ -148,67 +150,113 @@ func (t *Task) Execute() shared.TaskResult {
 	}
 
 	if len(asgList) == 0 {
-		return t.Context.Error("no autoscaling groups matched the specified criteria", nil)
+		return nil, nil, false, t.Context.Error("no autoscaling groups matched the specified criteria", nil)
 	}
 
 	if t.Context.Debug {
-		fmt.Printf("Found %d autoscaling groups to refresh:\n", len(asgList))
+		shared.Printf("Found %d autoscaling groups to refresh:\n", len(asgList))
 		for _, item := range asgList {
-			fmt.Printf("  %s\n", item)
+			shared.Printf("  %s\n", item)
 		}
-		fmt.Println("")
+		shared.Println("")
+	}
+
+	return asgClient, asgList, skipMatching, shared.TaskResult{}
+}
+
+// refreshPreferences returns the instance refresh preferences
+func refreshPreferences(skipMatching bool) *types.RefreshPreferences {
+	return &types.RefreshPreferences{
+		SkipMatching: aws.Bool(skipMatching),
+		AutoRollback: aws.Bool(false),
+
+		// AWS Defaults
+		ScaleInProtectedInstances: "Wait",
+		StandbyInstances:          "Wait",
+
+		// This implements the "launch before terminating" policy
+		MaxHealthyPercentage: aws.Int32(110),
+		MinHealthyPercentage: aws.Int32(100),
+
+		// Give instances time to warm up
+		InstanceWarmup: aws.Int32(300),
+	}
+}
+
+func (t *Task) Execute() shared.TaskResult {
+	asgClient, asgList, skipMatching, result := t.prepare()
+	if asgClient == nil {
+		return result
 	}
 
 	// Set up the results map
 	asgResults := make(map[string]string)
 	success := true
+	changed := false
+
+	if t.Context.DryRun {
+		for _, asg := range asgList {
+			asgResults[asg] = shared.Placeholder("refresh")
+		}
+		return t.Context.DryRunResult(fmt.Sprintf("refresh %d autoscaling group(s)", len(asgList)),
+			map[string]any{"asg_refresh_count": len(asgList), "asg_refresh_results": asgResults})
+	}
 
 	// Iterate over the list of autoscaling groups and refresh them
 	for _, asg := range asgList {
 
-		if t.Context.DryRun {
-			asgResults[asg] = "success"
+		// Refresh the instances in the ASG
+		_, err := asgClient.StartInstanceRefresh(context.TODO(),
+			&autoscaling.StartInstanceRefreshInput{
+				AutoScalingGroupName: &asg,
+				Preferences:          refreshPreferences(skipMatching),
+			})
+		if err != nil {
+			asgResults[asg] = fmt.Sprintf("Instance Refresh failed: %s", err.Error())
+			success = false
 		} else {
-
-			// Refresh the instances in the ASG
-			_, err = asgClient.StartInstanceRefresh(context.TODO(),
-				&autoscaling.StartInstanceRefreshInput{
-					AutoScalingGroupName: &asg,
-					Preferences: &types.RefreshPreferences{
-						SkipMatching: aws.Bool(skipMatching),
-						AutoRollback: aws.Bool(false),
-
-						// AWS Defaults
-						ScaleInProtectedInstances: "Wait",
-						StandbyInstances:          "Wait",
-
-						// This implements the "launch before terminating" policy
-						MaxHealthyPercentage: aws.Int32(110),
-						MinHealthyPercentage: aws.Int32(100),
-
-						// Give instances time to warm up
-						InstanceWarmup: aws.Int32(300),
-					},
-				})
-			if err != nil {
-				asgResults[asg] = fmt.Sprintf("Instance Refresh failed: %s", err.Error())
-				success = false
-			} else {
-				asgResults[asg] = "success"
-			}
+			// A started refresh is reported as a change even if skip_matching leaves it nothing to
+			// replace, as AWS only decides which instances match once the refresh runs
+			asgResults[asg] = "success"
+			changed = true
 		}
 	}
 
-	var msg string
-	if t.Context.DryRun {
-		msg = "Dry run, ASG refresh simulated"
-	} else {
-		msg = "AWS Autoscaling Groups refreshed"
-	}
-	return t.Context.Result(
+	r := t.Context.Result(
 		success,
-		msg,
+		"AWS Autoscaling Groups refreshed",
 		map[string]any{"asg_refresh_count": len(asgList), "asg_refresh_results": asgResults})
+	r.Changed = changed
+	return r
+}
+
+// Plan describes the changes Execute would make, without making them
+func (t *Task) Plan() shared.TaskResult {
+	asgClient, asgList, skipMatching, result := t.prepare()
+	if asgClient == nil {
+		return result
+	}
+
+	prefs := refreshPreferences(skipMatching)
+	details := map[string]any{
+		"skip_matching":          skipMatching,
+		"min_healthy_percentage": aws.ToInt32(prefs.MinHealthyPercentage),
+		"max_healthy_percentage": aws.ToInt32(prefs.MaxHealthyPercentage),
+		"instance_warmup":        aws.ToInt32(prefs.InstanceWarmup),
+	}
+
+	asgResults := make(map[string]string)
+	changes := make([]shared.PlannedChange, 0, len(asgList))
+	for _, asg := range asgList {
+		asgResults[asg] = shared.Placeholder("refresh")
+		changes = append(changes, shared.PlannedChange{
+			Action:      "StartInstanceRefresh",
+			Target:      asg,
+			Description: fmt.Sprintf("refresh instances with MinHealthy %d%%, MaxHealthy %d%%", aws.ToInt32(prefs.MinHealthyPercentage), aws.ToInt32(prefs.MaxHealthyPercentage)),
+			Details:     details,
+		})
+	}
+	return t.Context.Planned(changes, map[string]any{"asg_refresh_count": len(asgList), "asg_refresh_results": asgResults})
 }
 
 func foundInList(list []string, item string) bool {
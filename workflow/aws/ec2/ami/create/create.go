@@ -33,14 +33,13 @@ type Task struct {
 func init() {
 	shared.RegisterTask("aws_ec2_ami_create", func(context shared.TaskContext) shared.Task {
 		return &Task{Context: context}
-	})
+	}, shared.Mutating())
 }
 
-func (t *Task) Execute() shared.TaskResult {
-	var err error
-
-	if err = json.Unmarshal(t.Context.Instructions, t); err != nil {
-		return t.Context.Error("failed to deserialize data to Task", err)
+// prepare deserializes the task and resolves its variables
+func (t *Task) prepare() error {
+	if err := json.Unmarshal(t.Context.Instructions, t); err != nil {
+		return err
 	}
 
 	// Resolve input variables
@@ -49,6 +48,15 @@ func (t *Task) Execute() shared.TaskResult {
 	if t.Context.Debug {
 		shared.DumpTask(t)
 	}
+	return nil
+}
+
+func (t *Task) Execute() shared.TaskResult {
+	var err error
+
+	if err = t.prepare(); err != nil {
+		return t.Context.Error("failed to deserialize data to Task", err)
+	}
 
 	// Resolve environment file
 	envFile := shared.SelectEnv(t.Env, t.Context.Env)
@@ -56,7 +64,8 @@ func (t *Task) Execute() shared.TaskResult {
 	amazonInstance, err := cloudaws.New(
 		cloudaws.WithRegion(t.Region),
 		cloudaws.WithEnvironment(envFile),
-		cloudaws.WithProfile(t.Profile))
+		cloudaws.WithProfile(t.Profile),
+		cloudaws.WithTaskContext(&t.Context))
 	if err != nil || amazonInstance == nil {
 		return t.Context.Error("failed to create AWS client", err)
 	}
@@ -89,11 +98,35 @@ func (t *Task) Execute() shared.TaskResult {
 	result, err := client.CreateImage(context.TODO(), input)
 	if err != nil {
 		if t.Context.DryRun && shared.DryRunErrCheck(err) {
-			return t.Context.Result(true, fmt.Sprintf("Dryrun, AWS API returned: %s", err.Error()), map[string]string{"image_id": "AMI-none-dry-run"})
+			return t.Context.DryRunResult(fmt.Sprintf("create AMI %s from instance %s", t.InstanceName, t.InstanceID),
+				map[string]string{"image_id": shared.Placeholder("image_id")})
 		}
 		return t.Context.Error("error creating image", err)
 	}
 	imageId = *result.ImageId
 
-	return t.Context.Result(true, fmt.Sprintf("AWS AMI %s created", imageId), map[string]string{"image_id": imageId})
+	r := t.Context.Result(true, fmt.Sprintf("AWS AMI %s created", imageId), map[string]string{"image_id": imageId})
+	r.Changed = true
+	return r
+}
+
+// Plan describes the change Execute would make, without making it
+func (t *Task) Plan() shared.TaskResult {
+	if err := t.prepare(); err != nil {
+		return t.Context.Error("failed to deserialize data to Task", err)
+	}
+
+	return t.Context.Planned([]shared.PlannedChange{
+		{
+			Action:      "CreateImage",
+			Target:      t.InstanceID,
+			Description: fmt.Sprintf("create AMI %s from instance %s", t.InstanceName, t.InstanceID),
+			Details: map[string]any{
+				"name":        t.InstanceName,
+				"description": t.Description,
+				"no_reboot":   t.NoReboot,
+				"tags":        t.Tags,
+			},
+		},
+	}, map[string]string{"image_id": shared.Placeholder("image_id")})
 }
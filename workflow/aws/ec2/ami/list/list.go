@@ -47,15 +47,16 @@ func (t *Task) Execute() shared.TaskResult {
 	amazonInstance, err := cloudaws.New(
 		cloudaws.WithRegion(t.Region),
 		cloudaws.WithEnvironment(envFile),
-		cloudaws.WithProfile(t.Profile))
+		cloudaws.WithProfile(t.Profile),
+		cloudaws.WithTaskContext(&t.Context))
 	if err != nil || amazonInstance == nil {
 		return t.Context.Error("failed to create AWS client", err)
 	}
 
 	client := amazonInstance.EC2Client()
+	// Describing images is read-only, so it is performed in a dry run as well
 	req := &ec2.DescribeImagesInput{
 		Filters: cloudaws.FiltersToEC2(t.Filters),
-		DryRun:  &t.Context.DryRun,
 	}
 	if t.Owner != "" {
 		req.Owners = []string{t.Owner}
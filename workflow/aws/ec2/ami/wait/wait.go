@@ -54,7 +54,8 @@ func (t *Task) Execute() shared.TaskResult {
 	amazonInstance, err := cloudaws.New(
 		cloudaws.WithRegion(t.Region),
 		cloudaws.WithEnvironment(envFile),
-		cloudaws.WithProfile(t.Profile))
+		cloudaws.WithProfile(t.Profile),
+		cloudaws.WithTaskContext(&t.Context))
 	if err != nil || amazonInstance == nil {
 		return t.Context.Error("failed to create AWS client", err)
 	}
@@ -76,7 +77,7 @@ func (t *Task) Execute() shared.TaskResult {
 		}
 
 		if t.Context.Debug {
-			fmt.Println("Checking image status...")
+			shared.Println("Checking image status...")
 		}
 
 		resp, err := client.DescribeImages(context.TODO(), &ec2.DescribeImagesInput{
@@ -84,9 +85,11 @@ func (t *Task) Execute() shared.TaskResult {
 		})
 		if err != nil || len(resp.Images) == 0 {
 			if t.Context.Debug {
-				fmt.Printf("Failed to get image status: %s\n", err)
+				shared.Printf("Failed to get image status: %s\n", err)
+			}
+			if !t.Context.Sleep(15 * time.Second) {
+				return t.Context.Error(fmt.Sprintf("cancelled while waiting for image %s", t.ImageId), nil)
 			}
-			time.Sleep(15 * time.Second)
 			continue
 		}
 
@@ -95,8 +98,25 @@ func (t *Task) Execute() shared.TaskResult {
 		}
 
 		if t.Context.Debug {
-			fmt.Printf("Image status is %s, sleeping for 15 seconds...\n", resp.Images[0].State)
+			shared.Printf("Image status is %s, sleeping for 15 seconds...\n", resp.Images[0].State)
+		}
+		if !t.Context.Sleep(15 * time.Second) {
+			return t.Context.Error(fmt.Sprintf("cancelled while waiting for image %s", t.ImageId), nil)
 		}
-		time.Sleep(15 * time.Second)
 	}
 }
+
+// Plan reports the wait without waiting, since the planned changes the wait depends on are not made
+func (t *Task) Plan() shared.TaskResult {
+	if err := json.Unmarshal(t.Context.Instructions, t); err != nil {
+		return t.Context.Error("failed to deserialize data to Task", err)
+	}
+
+	shared.ProcessVars(t)
+
+	if t.ImageId == "" {
+		return t.Context.Error("image_id is null, nothing to wait for", nil)
+	}
+
+	return t.Context.Result(true, fmt.Sprintf("Plan mode, would wait for AMI %s to become available", t.ImageId), nil)
+}
@@ -51,7 +51,8 @@ func (t *Task) Execute() shared.TaskResult {
 	amazonInstance, err := cloudaws.New(
 		cloudaws.WithRegion(t.Region),
 		cloudaws.WithEnvironment(envFile),
-		cloudaws.WithProfile(t.Profile))
+		cloudaws.WithProfile(t.Profile),
+		cloudaws.WithTaskContext(&t.Context))
 	if err != nil || amazonInstance == nil {
 		return t.Context.Error("failed to create AWS client", err)
 	}
@@ -62,9 +63,9 @@ func (t *Task) Execute() shared.TaskResult {
 	}
 
 	client := amazonInstance.EC2Client()
+	// Describing instances is read-only, so it is performed in a dry run as well
 	req := &ec2.DescribeInstancesInput{
-		Filters: cloudaws.FiltersToEC2(t.Filters),
-		DryRun:  &t.Context.DryRun}
+		Filters: cloudaws.FiltersToEC2(t.Filters)}
 
 	// Set up the paginator
 	var instanceData []any
@@ -6,6 +6,7 @@ package stop
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -27,14 +28,14 @@ type Task struct {
 func init() {
 	shared.RegisterTask("aws_ec2_instance_stop", func(context shared.TaskContext) shared.Task {
 		return &Task{Context: context}
-	})
+	}, shared.Mutating())
 }
 
-func (t *Task) Execute() shared.TaskResult {
-	var err error
-
-	if err = json.Unmarshal(t.Context.Instructions, t); err != nil {
-		return t.Context.Error("failed to deserialize data", err)
+// prepare deserializes the task and creates the AWS client. If it fails, the client is nil and the
+// error result is returned.
+func (t *Task) prepare() (*cloudaws.CloudAWS, shared.TaskResult) {
+	if err := json.Unmarshal(t.Context.Instructions, t); err != nil {
+		return nil, t.Context.Error("failed to deserialize data", err)
 	}
 
 	// Resolve input variables
@@ -50,9 +51,31 @@ func (t *Task) Execute() shared.TaskResult {
 	amazonInstance, err := cloudaws.New(
 		cloudaws.WithRegion(t.Region),
 		cloudaws.WithEnvironment(envFile),
-		cloudaws.WithProfile(t.Profile))
+		cloudaws.WithProfile(t.Profile),
+		cloudaws.WithTaskContext(&t.Context))
 	if err != nil || amazonInstance == nil {
-		return t.Context.Error("failed to create AWS client", err)
+		return nil, t.Context.Error("failed to create AWS client", err)
+	}
+	return amazonInstance, shared.TaskResult{}
+}
+
+func (t *Task) Execute() shared.TaskResult {
+	amazonInstance, result := t.prepare()
+	if amazonInstance == nil {
+		return result
+	}
+
+	data := make(map[string]any)
+	data["instance_id"] = t.InstanceId
+
+	// Stopping an instance that is already stopped (or stopping) is a no-op
+	state, err := amazonInstance.InstanceState(t.InstanceId)
+	if err != nil {
+		return t.Context.Error("failed to obtain instance state", err)
+	}
+	data["previous_state"] = state
+	if state == "stopped" || state == "stopping" {
+		return t.Context.Result(true, fmt.Sprintf("AWS EC2 Instance already %s", state), data)
 	}
 
 	client := amazonInstance.EC2Client()
@@ -64,15 +87,50 @@ func (t *Task) Execute() shared.TaskResult {
 	_, err = client.StopInstances(context.TODO(), req)
 	if err != nil {
 		if t.Context.DryRun && shared.DryRunErrCheck(err) {
-			return t.Context.Result(true, fmt.Sprintf("Dryrun, AWS API returned: %s", err.Error()), nil)
+			return t.Context.DryRunResult(fmt.Sprintf("stop instance %s", t.InstanceId), data)
 		}
-		return t.Context.Error("failed to start instance", err)
+		return t.Context.Error("failed to stop instance", err)
 	}
 
-	data := make(map[string]any)
-	data["instance_id"] = t.InstanceId
-	return t.Context.Result(
+	r := t.Context.Result(
 		true,
 		"AWS EC2 Instance stopping",
 		data)
+	r.Changed = true
+	return r
+}
+
+// Plan describes the change Execute would make, without making it
+func (t *Task) Plan() shared.TaskResult {
+	amazonInstance, result := t.prepare()
+	if amazonInstance == nil {
+		return result
+	}
+
+	state, err := amazonInstance.InstanceState(t.InstanceId)
+	if err != nil {
+		return t.Context.Error("failed to obtain instance state", err)
+	}
+	data := map[string]any{"instance_id": t.InstanceId, "previous_state": state}
+	if state == "stopped" || state == "stopping" {
+		return t.Context.Planned(nil, data)
+	}
+
+	return t.Context.Planned([]shared.PlannedChange{
+		{
+			Action:      "StopInstances",
+			Target:      t.InstanceId,
+			Description: fmt.Sprintf("stop instance (currently %s)", state),
+			Details:     map[string]any{"force": t.Force},
+		},
+	}, data)
+}
+
+// Describe returns the instance the task acts on, including its tags, for use by policy rules
+func (t *Task) Describe() (map[string]any, error) {
+	amazonInstance, result := t.prepare()
+	if amazonInstance == nil {
+		return nil, errors.New(result.Msg)
+	}
+	return amazonInstance.DescribeInstance(t.InstanceId)
 }
@@ -60,7 +60,8 @@ func (t *Task) Execute() shared.TaskResult {
 	amazonInstance, err := cloudaws.New(
 		cloudaws.WithRegion(t.Region),
 		cloudaws.WithEnvironment(envFile),
-		cloudaws.WithProfile(t.Profile))
+		cloudaws.WithProfile(t.Profile),
+		cloudaws.WithTaskContext(&t.Context))
 	if err != nil || amazonInstance == nil {
 		return t.Context.Error("failed to create AWS client", err)
 	}
@@ -70,7 +71,7 @@ func (t *Task) Execute() shared.TaskResult {
 
 	// First wait for the instance to reach the desired state
 	if t.Context.Debug {
-		fmt.Printf("Waiting for instance %s to reach state %s...\n", t.InstanceId, t.State)
+		shared.Printf("Waiting for instance %s to reach state %s...\n", t.InstanceId, t.State)
 	}
 
 	if t.Context.DryRun {
@@ -90,9 +91,11 @@ func (t *Task) Execute() shared.TaskResult {
 		})
 		if err != nil || len(resp.Reservations) == 0 {
 			if t.Context.Debug {
-				fmt.Printf("Failed to describe instance: %v\n", err)
+				shared.Printf("Failed to describe instance: %v\n", err)
+			}
+			if !t.Context.Sleep(10 * time.Second) {
+				return t.Context.Error(fmt.Sprintf("cancelled while waiting for instance %s", t.InstanceId), nil)
 			}
-			time.Sleep(10 * time.Second)
 			continue
 		}
 
@@ -110,17 +113,19 @@ func (t *Task) Execute() shared.TaskResult {
 			}
 
 			if t.Context.Debug {
-				fmt.Printf("instance state is '%s', waiting for '%s'...\n", currentState, t.State)
+				shared.Printf("instance state is '%s', waiting for '%s'...\n", currentState, t.State)
 			}
 		}
-		time.Sleep(10 * time.Second)
+		if !t.Context.Sleep(10 * time.Second) {
+			return t.Context.Error(fmt.Sprintf("cancelled while waiting for instance %s", t.InstanceId), nil)
+		}
 	}
 
 	// If the desired state is "running", wait for status checks to pass
 	if t.State == "running" {
 
 		if t.Context.Debug {
-			fmt.Printf("Waiting for instance %s to pass status checks...\n", t.InstanceId)
+			shared.Printf("Waiting for instance %s to pass status checks...\n", t.InstanceId)
 		}
 
 		// Wait until the instance passes status checks or the time limit is reached
@@ -138,9 +143,11 @@ func (t *Task) Execute() shared.TaskResult {
 
 			if err != nil || len(statusResp.InstanceStatuses) == 0 {
 				if t.Context.Debug {
-					fmt.Printf("Failed to get instance status: %v\n", err)
+					shared.Printf("Failed to get instance status: %v\n", err)
+				}
+				if !t.Context.Sleep(10 * time.Second) {
+					return t.Context.Error(fmt.Sprintf("cancelled while waiting for instance %s", t.InstanceId), nil)
 				}
-				time.Sleep(10 * time.Second)
 				continue
 			}
 
@@ -150,7 +157,7 @@ func (t *Task) Execute() shared.TaskResult {
 
 			if systemStatus == "ok" && instanceStatus == "ok" {
 				if t.Context.Debug {
-					fmt.Printf("Instance %s passed all status checks\n\n", t.InstanceId)
+					shared.Printf("Instance %s passed all status checks\n\n", t.InstanceId)
 				}
 				return t.Context.Result(true,
 					fmt.Sprintf("instance %s is running and passed all status checks", t.InstanceId),
@@ -158,12 +165,30 @@ func (t *Task) Execute() shared.TaskResult {
 			}
 
 			if t.Context.Debug {
-				fmt.Printf("System status: '%s', Instance status: '%s', waiting...\n", systemStatus, instanceStatus)
+				shared.Printf("System status: '%s', Instance status: '%s', waiting...\n", systemStatus, instanceStatus)
+			}
+			if !t.Context.Sleep(10 * time.Second) {
+				return t.Context.Error(fmt.Sprintf("cancelled while waiting for instance %s", t.InstanceId), nil)
 			}
-			time.Sleep(10 * time.Second)
 		}
 	}
 
 	return t.Context.Result(true, fmt.Sprintf("instance %s is in state %s", t.InstanceId, t.State),
 		map[string]any{"instance_id": t.InstanceId})
 }
+
+// Plan reports the wait without waiting, since the planned changes the wait depends on are not made
+func (t *Task) Plan() shared.TaskResult {
+	if err := json.Unmarshal(t.Context.Instructions, t); err != nil {
+		return t.Context.Error("failed to deserialize data to Task", err)
+	}
+
+	shared.ProcessVars(t)
+	t.State = strings.ToLower(t.State)
+
+	if t.State != "running" && t.State != "stopped" && t.State != "terminated" {
+		return t.Context.Error(fmt.Sprintf("invalid state %s", t.State), nil)
+	}
+
+	return t.Context.Result(true, fmt.Sprintf("Plan mode, would wait for instance %s to reach state %s", t.InstanceId, t.State), nil)
+}
@@ -30,15 +30,17 @@ type Task struct {
 func init() {
 	shared.RegisterTask("aws_ec2_lt_change_image", func(context shared.TaskContext) shared.Task {
 		return &Task{Context: context}
-	})
+	}, shared.Mutating())
 }
 
-func (t *Task) Execute() shared.TaskResult {
+// prepare deserializes the task, creates the AWS client, and obtains the current default version of
+// the launch template and its image. If it fails, the client is nil and the error result is returned.
+func (t *Task) prepare() (*ec2.Client, int64, string, shared.TaskResult) {
 	var err error
 	var defaultVersion int64
 
 	if err = json.Unmarshal(t.Context.Instructions, t); err != nil {
-		return t.Context.Error("failed to deserialize data", err)
+		return nil, 0, "", t.Context.Error("failed to deserialize data", err)
 	}
 
 	// Resolve input variables
@@ -50,9 +52,10 @@ func (t *Task) Execute() shared.TaskResult {
 	amazonInstance, err := cloudaws.New(
 		cloudaws.WithRegion(t.Region),
 		cloudaws.WithEnvironment(envFile),
-		cloudaws.WithProfile(t.Profile))
+		cloudaws.WithProfile(t.Profile),
+		cloudaws.WithTaskContext(&t.Context))
 	if err != nil || amazonInstance == nil {
-		return t.Context.Error("failed to create AWS client", err)
+		return nil, 0, "", t.Context.Error("failed to create AWS client", err)
 	}
 
 	client := amazonInstance.EC2Client()
@@ -64,22 +67,58 @@ func (t *Task) Execute() shared.TaskResult {
 		Filters:          cloudaws.FiltersToEC2(t.Filters),
 	})
 	if err != nil {
-		return t.Context.Error(fmt.Sprintf("failed to describe default launch template version for %s", t.LaunchTemplateId), err)
+		return nil, 0, "", t.Context.Error(fmt.Sprintf("failed to describe default launch template version for %s", t.LaunchTemplateId), err)
 	}
 
-	// Extract the default version from the response
+	// Extract the default version and its image from the response
+	var currentImageId string
 	if len(resp.LaunchTemplateVersions) > 0 {
 		defaultVersion = *resp.LaunchTemplateVersions[0].VersionNumber
+		if resp.LaunchTemplateVersions[0].LaunchTemplateData != nil {
+			currentImageId = aws.ToString(resp.LaunchTemplateVersions[0].LaunchTemplateData.ImageId)
+		}
 	} else {
-		return t.Context.Error(fmt.Sprintf("failed to obtain default launch template version for %s", t.LaunchTemplateId), nil)
+		return nil, 0, "", t.Context.Error(fmt.Sprintf("failed to obtain default launch template version for %s", t.LaunchTemplateId), nil)
 	}
 
 	if defaultVersion < 1 {
-		return t.Context.Error(fmt.Sprintf("launch template version for %s is less than 1, aborting", t.LaunchTemplateId), nil)
+		return nil, 0, "", t.Context.Error(fmt.Sprintf("launch template version for %s is less than 1, aborting", t.LaunchTemplateId), nil)
 	}
+	return client, defaultVersion, currentImageId, shared.TaskResult{}
+}
 
-	// Create a new version using the current default as a template
+func (t *Task) Execute() shared.TaskResult {
+	client, defaultVersion, currentImageId, result := t.prepare()
+	if client == nil {
+		return result
+	}
 	defaultVersionStr := fmt.Sprintf("%v", defaultVersion)
+
+	// If the default version already uses the image there is nothing to do. Creating another
+	// identical version would only clutter the launch template history.
+	if currentImageId != "" && currentImageId == t.ImageId {
+		current, err := client.DescribeLaunchTemplates(context.TODO(), &ec2.DescribeLaunchTemplatesInput{
+			LaunchTemplateIds: []string{t.LaunchTemplateId},
+		})
+		if err != nil {
+			return t.Context.Error(fmt.Sprintf("failed to describe launch template %s", t.LaunchTemplateId), err)
+		}
+		if len(current.LaunchTemplates) == 0 {
+			return t.Context.Error(fmt.Sprintf("launch template %s not found", t.LaunchTemplateId), nil)
+		}
+		return t.Context.Result(
+			true,
+			fmt.Sprintf("AWS EC2 Launch Template default version %s already uses image %s", defaultVersionStr, t.ImageId),
+			map[string]any{
+				"lt_id":            t.LaunchTemplateId,
+				"image_id":         t.ImageId,
+				"previous_default": defaultVersionStr,
+				"new_version":      defaultVersionStr,
+				"launch_template":  shared.SelectFields(current.LaunchTemplates[0], t.Fields),
+			})
+	}
+
+	// Create a new version using the current default as a template
 	input := &ec2.CreateLaunchTemplateVersionInput{
 		LaunchTemplateId: aws.String(t.LaunchTemplateId),
 		SourceVersion:    aws.String(defaultVersionStr),
@@ -92,7 +131,15 @@ func (t *Task) Execute() shared.TaskResult {
 	newTemplate, err := client.CreateLaunchTemplateVersion(context.TODO(), input)
 	if err != nil {
 		if t.Context.DryRun && shared.DryRunErrCheck(err) {
-			return t.Context.Result(true, fmt.Sprintf("Dryrun, AWS API returned: %s", err.Error()), nil)
+			return t.Context.DryRunResult(
+				fmt.Sprintf("create launch template version from v%s with image %s and set it as default", defaultVersionStr, t.ImageId),
+				map[string]any{
+					"lt_id":            t.LaunchTemplateId,
+					"image_id":         t.ImageId,
+					"previous_default": defaultVersionStr,
+					"new_version":      shared.Placeholder("new_version"),
+					"launch_template":  shared.Placeholder("launch_template"),
+				})
 		}
 		return t.Context.Error("failed to create new launch template version", err)
 	}
@@ -107,6 +154,7 @@ func (t *Task) Execute() shared.TaskResult {
 	newDefault, err := client.ModifyLaunchTemplate(context.TODO(), &ec2.ModifyLaunchTemplateInput{
 		LaunchTemplateId: aws.String(t.LaunchTemplateId),
 		DefaultVersion:   aws.String(newVersion),
+		DryRun:           aws.Bool(t.Context.DryRun),
 	})
 	if err != nil {
 		return t.Context.Error("failed to set new launch template version as default", err)
@@ -121,8 +169,43 @@ func (t *Task) Execute() shared.TaskResult {
 
 	data["launch_template"] = shared.SelectFields(newDefault.LaunchTemplate, t.Fields)
 
-	return t.Context.Result(
+	r := t.Context.Result(
 		true,
 		fmt.Sprintf("AWS EC2 Launch Template version %s created and set as default", newVersion),
 		data)
+	r.Changed = true
+	return r
+}
+
+// Plan describes the changes Execute would make, without making them
+func (t *Task) Plan() shared.TaskResult {
+	client, defaultVersion, currentImageId, result := t.prepare()
+	if client == nil {
+		return result
+	}
+
+	data := map[string]any{
+		"lt_id":            t.LaunchTemplateId,
+		"image_id":         t.ImageId,
+		"previous_default": fmt.Sprintf("%v", defaultVersion),
+		"new_version":      fmt.Sprintf("%v", defaultVersion),
+	}
+	if currentImageId != "" && currentImageId == t.ImageId {
+		return t.Context.Planned(nil, data)
+	}
+
+	data["new_version"] = shared.Placeholder("new_version")
+	return t.Context.Planned([]shared.PlannedChange{
+		{
+			Action:      "CreateLaunchTemplateVersion",
+			Target:      t.LaunchTemplateId,
+			Description: fmt.Sprintf("create launch template version from v%d with image %s (currently %s)", defaultVersion, t.ImageId, currentImageId),
+			Details:     map[string]any{"source_version": defaultVersion, "image_id": t.ImageId, "previous_image_id": currentImageId},
+		},
+		{
+			Action:      "ModifyLaunchTemplate",
+			Target:      t.LaunchTemplateId,
+			Description: "set the new version as the default version",
+		},
+	}, data)
 }
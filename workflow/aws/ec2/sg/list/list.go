@@ -45,15 +45,16 @@ func (t *Task) Execute() shared.TaskResult {
 	amazonInstance, err := cloudaws.New(
 		cloudaws.WithRegion(t.Region),
 		cloudaws.WithEnvironment(envFile),
-		cloudaws.WithProfile(t.Profile))
+		cloudaws.WithProfile(t.Profile),
+		cloudaws.WithTaskContext(&t.Context))
 	if err != nil || amazonInstance == nil {
 		return t.Context.Error("failed to create AWS client", err)
 	}
 
 	client := amazonInstance.EC2Client()
+	// Describing security groups is read-only, so it is performed in a dry run as well
 	req := &ec2.DescribeSecurityGroupsInput{
-		Filters: cloudaws.FiltersToEC2(t.Filters),
-		DryRun:  &t.Context.DryRun}
+		Filters: cloudaws.FiltersToEC2(t.Filters)}
 
 	// Set up the paginator
 	var sgData []any
@@ -0,0 +1,30 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package workflow
+
+import (
+	"strings"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// builtinFunc is the implementation of an engine built-in task. Built-in tasks operate on the workflow
+// itself (handlers, macros, etc.), so unlike registered tasks they receive the workflow position and
+// the raw task in addition to the task context.
+type builtinFunc func(pos position, rawTask map[string]any, taskContext shared.TaskContext) shared.TaskResult
+
+// builtin returns the implementation of an engine built-in task, or nil if the task is not built in
+func (w *Workflow) builtin(taskType string) builtinFunc {
+	switch {
+	case taskType == TaskFlushHandlers:
+		return w.flushHandlersTask
+	case taskType == TaskWorkflowRun:
+		return w.workflowRunTask
+	case taskType == TaskAwait:
+		return w.awaitTask
+	case strings.HasPrefix(taskType, MacroPrefix):
+		return w.macroTask
+	}
+	return nil
+}
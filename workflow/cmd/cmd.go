@@ -5,13 +5,16 @@ package cmd
 
 import (
 	"encoding/json"
+	"fmt"
 	"os/exec"
+	"strings"
 
 	"github.com/OpsBlade/OpsBlade/shared"
 )
 
 type Task struct {
 	Context shared.TaskContext `yaml:"context" json:"context"` // Task context
+	Env     string             `yaml:"env" json:"env"`         // Optional file of variables added to the command's environment
 	Cmd     string             `yaml:"cmd" json:"cmd"`         // Subject of the message
 	Args    []string           `yaml:"args" json:"args"`       // Body of the message
 	NoFail  bool               `yaml:"no_fail" json:"no_fail"` // Do not fail if the command returns a non-zero exit code
@@ -20,7 +23,7 @@ type Task struct {
 func init() {
 	shared.RegisterTask("cmd_exec", func(context shared.TaskContext) shared.Task {
 		return &Task{Context: context}
-	})
+	}, shared.Mutating())
 }
 
 func (t *Task) Execute() shared.TaskResult {
@@ -39,19 +42,27 @@ func (t *Task) Execute() shared.TaskResult {
 		shared.DumpTask(t)
 	}
 
+	data["cmd"] = t.Cmd
+	data["cmd_args"] = t.Args
+
+	// Read the environment file, if one is provided, so that a dry run reports a missing file
+	env, err := shared.LoadEnv(shared.SelectEnv(t.Env, t.Context.Env))
+	if err != nil {
+		return t.Context.Error("failed to load environment", err)
+	}
+
 	if t.Context.DryRun {
-		return t.Context.Result(true, "DryRun, command not executed", data)
+		data["cmd_output"] = shared.Placeholder("cmd_output")
+		return t.Context.DryRunResult(fmt.Sprintf("run %s", strings.Join(append([]string{t.Cmd}, t.Args...), " ")), data)
 	}
 
 	// Execute command using os/exec
 	cmd := exec.Command(t.Cmd, t.Args...)
+	cmd.Env = env.Environ()
 	output, err := cmd.CombinedOutput()
 
 	// Store output in data map
-	outputStr := string(output)
-	data["cmd"] = t.Cmd
-	data["cmd_args"] = t.Args
-	data["cmd_output"] = outputStr
+	data["cmd_output"] = string(output)
 
 	// Handle error
 	if err != nil {
@@ -63,3 +74,20 @@ func (t *Task) Execute() shared.TaskResult {
 
 	return t.Context.Result(true, "Command executed successfully", data)
 }
+
+// Plan describes the command Execute would run, without running it
+func (t *Task) Plan() shared.TaskResult {
+	if err := json.Unmarshal(t.Context.Instructions, t); err != nil {
+		return t.Context.Error("failed to deserialize data", err)
+	}
+
+	shared.ProcessVars(t)
+
+	return t.Context.Planned([]shared.PlannedChange{
+		{
+			Action:      "Exec",
+			Target:      t.Cmd,
+			Description: fmt.Sprintf("run %s", strings.Join(append([]string{t.Cmd}, t.Args...), " ")),
+		},
+	}, map[string]any{"cmd": t.Cmd, "cmd_args": t.Args, "cmd_output": shared.Placeholder("cmd_output")})
+}
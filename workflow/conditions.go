@@ -0,0 +1,123 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// parseCriteria converts a raw workflow value (a single criterion or a list of criteria) into
+// a slice of shared.SelectCriteria. The value is round-tripped through JSON so that numbers
+// are float64, matching the representation of the result data they are compared with.
+func parseCriteria(raw any) ([]shared.SelectCriteria, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var criteria []shared.SelectCriteria
+	if _, ok := raw.(map[string]any); ok {
+		var single shared.SelectCriteria
+		if err = json.Unmarshal(data, &single); err != nil {
+			return nil, err
+		}
+		criteria = append(criteria, single)
+	} else if err = json.Unmarshal(data, &criteria); err != nil {
+		return nil, err
+	}
+
+	for _, c := range criteria {
+		if c.Field == "" {
+			return nil, fmt.Errorf("criteria field is missing")
+		}
+		if !shared.IsValidComparisonOperator(c.Compare) {
+			return nil, fmt.Errorf("invalid comparison operator: %s", c.Compare)
+		}
+	}
+	return criteria, nil
+}
+
+// evaluateCondition evaluates a boolean or a list of select criteria against the result data
+func evaluateCondition(raw any, data map[string]any) (bool, error) {
+	if b, ok := raw.(bool); ok {
+		return b, nil
+	}
+
+	criteria, err := parseCriteria(raw)
+	if err != nil {
+		return false, err
+	}
+
+	if data == nil {
+		data = make(map[string]any)
+	}
+	return shared.ApplySelectionCriteria(data, criteria)
+}
+
+// evaluateResult applies the task's changed_when, failed_when, and ignore_errors settings to the result
+// returned by the task. The task's own outcome is preserved in TaskSuccess.
+func evaluateResult(rawTask map[string]any, result shared.TaskResult) shared.TaskResult {
+	result.TaskSuccess = result.Success
+	if result.Skipped {
+		return result
+	}
+
+	result = applyChangedWhen(rawTask, result)
+	result = applyFailedWhen(rawTask, result)
+
+	// The failure is recorded, but the workflow is allowed to continue
+	if ignore, ok := rawTask["ignore_errors"].(bool); ok && ignore && !result.Success {
+		result.Ignored = true
+	}
+	return result
+}
+
+// applyChangedWhen overrides the changed status reported by a task if changed_when is present.
+// An invalid condition fails the task.
+func applyChangedWhen(rawTask map[string]any, result shared.TaskResult) shared.TaskResult {
+	raw, ok := rawTask["changed_when"]
+	if !ok || raw == nil || !result.Success {
+		return result
+	}
+
+	changed, err := evaluateCondition(raw, result.Data)
+	if err != nil {
+		result.Success = false
+		result.Changed = false
+		result.Msg = fmt.Sprintf("invalid changed_when: %s", err.Error())
+		return result
+	}
+	result.Changed = changed
+	return result
+}
+
+// applyFailedWhen fails a successful task if its failed_when condition is met. It does not turn
+// a failed task into a successful one; use ignore_errors for that.
+func applyFailedWhen(rawTask map[string]any, result shared.TaskResult) shared.TaskResult {
+	raw, ok := rawTask["failed_when"]
+	if !ok || raw == nil || !result.Success {
+		return result
+	}
+
+	failed, err := evaluateCondition(raw, result.Data)
+	if err != nil {
+		result.Success = false
+		result.Msg = fmt.Sprintf("invalid failed_when: %s", err.Error())
+		return result
+	}
+	if !failed {
+		return result
+	}
+
+	result.Success = false
+	result.Msg = fmt.Sprintf("failed_when condition met: %s", result.Msg)
+	if errorMessage, ok := rawTask["error_message"].(string); ok && errorMessage != "" {
+		result.Msg = fmt.Sprintf("%s\n\n%s\n", result.Msg, errorMessage)
+		result.ErrorMessage = errorMessage
+	}
+	return result
+}
@@ -0,0 +1,257 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package workflow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// standIn is a local HTTP server that stands in for AWS, Jira, and Slack. Read-only requests receive
+// canned responses, AWS requests with the DryRun flag receive a DryRunOperation error, and any other
+// request is recorded as a mutating request.
+type standIn struct {
+	mu       sync.Mutex
+	mutating []string
+}
+
+// awsResponses are the canned responses to read-only AWS actions
+var awsResponses = map[string]string{
+	"DescribeInstances":      describeInstances(slices.Sorted(maps.Keys(awsInstances))),
+	"DescribeImages":         `<DescribeImagesResponse><imagesSet/></DescribeImagesResponse>`,
+	"DescribeSecurityGroups": `<DescribeSecurityGroupsResponse><securityGroupInfo/></DescribeSecurityGroupsResponse>`,
+	"DescribeLaunchTemplateVersions": `<DescribeLaunchTemplateVersionsResponse><launchTemplateVersionSet><item>
+		<launchTemplateId>lt-0123</launchTemplateId><versionNumber>12</versionNumber><defaultVersion>true</defaultVersion>
+		<launchTemplateData><imageId>ami-old</imageId></launchTemplateData>
+		</item></launchTemplateVersionSet></DescribeLaunchTemplateVersionsResponse>`,
+	"DescribeAutoScalingGroups": `<DescribeAutoScalingGroupsResponse><DescribeAutoScalingGroupsResult><AutoScalingGroups><member>
+		<AutoScalingGroupName>asg-a</AutoScalingGroupName><MinSize>1</MinSize><MaxSize>2</MaxSize><DesiredCapacity>1</DesiredCapacity>
+		<LaunchTemplate><LaunchTemplateId>lt-0123</LaunchTemplateId></LaunchTemplate>
+		</member></AutoScalingGroups></DescribeAutoScalingGroupsResult></DescribeAutoScalingGroupsResponse>`,
+	"DescribeInstanceRefreshes": `<DescribeInstanceRefreshesResponse><DescribeInstanceRefreshesResult><InstanceRefreshes/>
+		</DescribeInstanceRefreshesResult></DescribeInstanceRefreshesResponse>`,
+}
+
+// awsInstances are the instances DescribeInstances returns
+var awsInstances = map[string]string{
+	"i-stopped": `<item><instanceId>i-stopped</instanceId><instanceState><code>80</code><name>stopped</name></instanceState></item>`,
+	"i-running": `<item><instanceId>i-running</instanceId><instanceState><code>16</code><name>running</name></instanceState></item>`,
+}
+
+// describeInstances returns the DescribeInstances response for the instances
+func describeInstances(ids []string) string {
+	var items strings.Builder
+	for _, id := range ids {
+		items.WriteString(awsInstances[id])
+	}
+	return "<DescribeInstancesResponse><reservationSet><item><instancesSet>" + items.String() +
+		"</instancesSet></item></reservationSet></DescribeInstancesResponse>"
+}
+
+// dryRunOperation is the error AWS returns for a request with the DryRun flag that would have succeeded
+const dryRunOperation = `<Response><Errors><Error><Code>DryRunOperation</Code>
+	<Message>Request would have succeeded, but DryRun flag is set.</Message></Error></Errors><RequestID>1</RequestID></Response>`
+
+func (s *standIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// AWS query requests carry the action in a form-encoded body
+	if err := r.ParseForm(); err == nil && r.PostForm.Get("Action") != "" {
+		action := r.PostForm.Get("Action")
+		response, ok := awsResponses[action]
+		if id := r.PostForm.Get("InstanceId.1"); action == "DescribeInstances" && id != "" {
+			response = describeInstances([]string{id})
+		}
+		if ok {
+			w.Header().Set("Content-Type", "text/xml")
+			_, _ = fmt.Fprint(w, response)
+			return
+		}
+		if r.PostForm.Get("DryRun") == "true" {
+			w.Header().Set("Content-Type", "text/xml")
+			w.WriteHeader(http.StatusPreconditionFailed)
+			_, _ = fmt.Fprint(w, dryRunOperation)
+			return
+		}
+		s.record(fmt.Sprintf("AWS %s", action))
+		http.Error(w, "mutating request", http.StatusForbidden)
+		return
+	}
+
+	// Jira and Slack
+	if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/rest/api/2/issue/") {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"key":"OPS-1","fields":{"status":{"name":"Done"},"resolution":{"name":"Fixed"}}}`)
+		return
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		http.NotFound(w, r)
+		return
+	}
+	s.record(fmt.Sprintf("%s %s", r.Method, r.URL.Path))
+	http.Error(w, "mutating request", http.StatusForbidden)
+}
+
+func (s *standIn) record(request string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mutating = append(s.mutating, request)
+}
+
+func (s *standIn) take() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := s.mutating
+	s.mutating = nil
+	return requests
+}
+
+// TestDryRunContract runs every registered task in a dry run against local stand-ins for the external
+// services and checks that it honors the dry run contract: no mutating requests, no local side effects,
+// success, and a result that describes the would-be change.
+func TestDryRunContract(t *testing.T) {
+	server := httptest.NewServer(&standIn{})
+	defer server.Close()
+	services := server.Config.Handler.(*standIn)
+
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("AWS_ENDPOINT_URL", server.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("JIRA_URL", server.URL)
+	t.Setenv("JIRA_USER", "test")
+	t.Setenv("JIRA_TOKEN", "test")
+	t.Setenv("SLACK_WEBHOOK", server.URL+"/slack")
+
+	existing := filepath.Join(dir, "existing.txt")
+	if err := os.WriteFile(existing, []byte(`{"loaded": true}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	created := filepath.Join(dir, "created.txt")
+
+	// Instructions for every registered task. A new task must be added here.
+	fixtures := map[string]map[string]any{
+		"aws_ec2_instance_list":      {},
+		"aws_ec2_instance_start":     {"instance_id": "i-stopped"},
+		"aws_ec2_instance_stop":      {"instance_id": "i-running"},
+		"aws_ec2_instance_wait":      {"instance_id": "i-stopped", "state": "running", "limit": 60},
+		"aws_ec2_ami_list":           {},
+		"aws_ec2_ami_create":         {"instance_id": "i-running", "instance_name": "web-ami"},
+		"aws_ec2_ami_wait":           {"image_id": "ami-new", "limit": 60},
+		"aws_ec2_sg_list":            {},
+		"aws_ec2_lt_change_image":    {"lt_id": "lt-0123", "image_id": "ami-new"},
+		"aws_asg_list":               {},
+		"aws_asg_refresh":            {"launch_templates": []string{"lt-0123"}},
+		"aws_asg_describe_refreshes": {"asg_name": "asg-a"},
+		"jira_issue_check":           {"issue_id": "OPS-1", "required_status": "Done"},
+		"jira_issue_create":          {"project": "OPS", "issue_type": "Task", "summary": "Deploy"},
+		"jira_issue_comment":         {"issue_id": "OPS-1", "comment": "Deployed"},
+		"jira_issue_attach_file":     {"issue_id": "OPS-1", "file_name": existing},
+		"slack_send":                 {"subject": "Deployed", "body": "Done"},
+		"cmd_exec":                   {"cmd": "touch", "args": []string{created}},
+		"file_delete":                {"filename": existing},
+		"variables_set":              {"set": []map[string]any{{"name": "dryrun_test", "value": 1}}},
+		"variables_load":             {"filename": existing},
+		"variables_save":             {"filename": created},
+		"variables_dump":             {},
+		"exit_if":                    {"select": []map[string]any{{"field": "dryrun_exit", "compare": "equal", "value": "yes"}}},
+		"dryrun_or_die":              {},
+		"approval":                   {},
+		"sleep":                      {"sleep": 60},
+		"example":                    {},
+		"test_task":                  {},
+		"test_change":                {"data": map[string]any{"test_output": "value"}},
+	}
+
+	// The data keys a real run of each mutating task returns, which later tasks may consume. A dry run must
+	// return them as well. A new mutating task must be added here.
+	outputs := map[string][]string{
+		"aws_ec2_instance_start":  {"instance_id", "previous_state"},
+		"aws_ec2_instance_stop":   {"instance_id", "previous_state"},
+		"aws_ec2_ami_create":      {"image_id"},
+		"aws_ec2_lt_change_image": {"lt_id", "image_id", "previous_default", "new_version", "launch_template"},
+		"aws_asg_refresh":         {"asg_refresh_count", "asg_refresh_results"},
+		"jira_issue_create":       {"jira_issue_id", "jira_project"},
+		"jira_issue_comment":      {},
+		"jira_issue_attach_file":  {"jira_attached_file_name"},
+		"cmd_exec":                {"cmd", "cmd_args", "cmd_output"},
+		"file_delete":             {},
+		"test_change":             {"test_output"},
+	}
+
+	for _, taskType := range slices.Sorted(maps.Keys(shared.TaskRegistry)) {
+		t.Run(taskType, func(t *testing.T) {
+			fixture, ok := fixtures[taskType]
+			if !ok {
+				t.Fatalf("no dry run fixture for task %s", taskType)
+			}
+
+			rawTask := map[string]any{"name": "dry run " + taskType, "task": taskType}
+			for k, v := range fixture {
+				rawTask[k] = v
+			}
+			instructions, err := json.Marshal(rawTask)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			task := shared.TaskRegistry[taskType](shared.TaskContext{
+				DryRun:       true,
+				Name:         rawTask["name"].(string),
+				Task:         taskType,
+				Sequence:     1,
+				Instructions: instructions,
+			})
+			result := task.Execute()
+
+			if requests := services.take(); len(requests) > 0 {
+				t.Errorf("mutating requests in a dry run: %v", requests)
+			}
+			if _, err = os.Stat(existing); err != nil {
+				t.Errorf("file changed in a dry run: %v", err)
+			}
+			if _, err = os.Stat(created); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("file created in a dry run")
+				_ = os.Remove(created)
+			}
+			if !result.Success {
+				t.Fatalf("dry run failed: %s", result.Msg)
+			}
+			if result.Changed && !strings.HasPrefix(result.Msg, "Dry run: would ") {
+				t.Errorf("changed result does not describe the would-be change: %s", result.Msg)
+			}
+			if !shared.IsMutating(taskType) {
+				return
+			}
+
+			// The fixtures of mutating tasks describe a change, which a dry run must report
+			if !result.Changed {
+				t.Errorf("the would-be change is not reported as changed: %s", result.Msg)
+			}
+			keys, ok := outputs[taskType]
+			if !ok {
+				t.Fatalf("no dry run outputs for mutating task %s", taskType)
+			}
+			for _, key := range keys {
+				if _, ok = result.Data[key]; !ok {
+					t.Errorf("output %s is missing from the dry run data: %v", key, result.Data)
+				}
+			}
+		})
+	}
+}
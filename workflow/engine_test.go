@@ -0,0 +1,962 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OpsBlade/OpsBlade/audit"
+	"github.com/OpsBlade/OpsBlade/lock"
+	"github.com/OpsBlade/OpsBlade/policy"
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// testGates are closed by tests to release test tasks that block on them
+var testGates sync.Map
+
+// testTask is a task whose behaviour is set by its instructions. It is registered as test_task, and as
+// test_change, which is mutating.
+type testTask struct {
+	Context    shared.TaskContext `json:"context"`
+	Fail       bool               `json:"fail"`        // Fail with msg
+	Msg        string             `json:"msg"`         // Message of the result
+	Changed    bool               `json:"changed"`     // Report a change
+	Data       map[string]any     `json:"data"`        // Data of the result
+	Block      string             `json:"block"`       // Wait for the test gate, ignoring cancellation
+	WaitCancel bool               `json:"wait_cancel"` // Wait until the task is cancelled
+	Sleep      int                `json:"sleep"`       // Milliseconds to sleep before completing
+}
+
+func init() {
+	shared.RegisterTask("test_task", func(context shared.TaskContext) shared.Task {
+		return &testTask{Context: context}
+	})
+	shared.RegisterTask("test_change", func(context shared.TaskContext) shared.Task {
+		return &testTask{Context: context}
+	}, shared.Mutating())
+}
+
+func (t *testTask) Execute() shared.TaskResult {
+	if err := json.Unmarshal(t.Context.Instructions, t); err != nil {
+		return t.Context.Error("failed to deserialize data", err)
+	}
+	shared.ProcessVars(t)
+
+	if t.Context.DryRun {
+		return t.Context.DryRunResult("run a test task", t.Data)
+	}
+	if t.Block != "" {
+		gate, _ := testGates.Load(t.Block)
+		<-gate.(chan struct{})
+	}
+	if t.Sleep > 0 {
+		time.Sleep(time.Duration(t.Sleep) * time.Millisecond)
+	}
+	if t.WaitCancel {
+		<-t.Context.Cancel
+		return t.Context.Error("cancelled", nil)
+	}
+	if t.Fail {
+		return t.Context.Error(t.Msg, nil)
+	}
+	r := t.Context.Result(true, t.Msg, t.Data)
+	r.Changed = t.Changed
+	return r
+}
+
+// recorder is a callback that records the task information, results, and events of a workflow, and a log
+// of them in the order they were received
+type recorder struct {
+	mu      sync.Mutex
+	log     []string
+	results []shared.TaskResult
+	events  []shared.WorkflowEvent
+	starts  []shared.TaskInfo
+}
+
+func (r *recorder) OnStart(info shared.TaskInfo) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.log = append(r.log, "start:"+info.Name)
+	r.starts = append(r.starts, info)
+	return true
+}
+
+func (r *recorder) OnStop(result shared.TaskResult) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.log = append(r.log, "stop:"+result.Name)
+	r.results = append(r.results, result)
+	return result.Success || result.Ignored
+}
+
+func (r *recorder) OnEvent(event shared.WorkflowEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.log = append(r.log, "event:"+event.MessageType)
+	r.events = append(r.events, event)
+}
+
+// result returns the last result of the named task
+func (r *recorder) result(t *testing.T, name string) shared.TaskResult {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.results) - 1; i >= 0; i-- {
+		if r.results[i].Name == name {
+			return r.results[i]
+		}
+	}
+	t.Fatalf("no result of %s in %v", name, r.log)
+	return shared.TaskResult{}
+}
+
+// index returns the position of an entry in the log, or -1
+func (r *recorder) index(entry string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Index(r.log, entry)
+}
+
+// writeWorkflow writes a workflow file in dir and returns its name
+func writeWorkflow(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	filename := filepath.Join(dir, name)
+	if err := os.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// runWorkflow loads and executes a workflow with its own variables, and returns the recorder and the
+// result of Execute
+func runWorkflow(t *testing.T, content string, options ...Option) (*recorder, bool) {
+	t.Helper()
+	previous := shared.SwapVars(nil)
+	defer shared.SwapVars(previous)
+
+	rec := &recorder{}
+	w := New(append([]Option{WithCallback(rec)}, options...)...)
+	if err := w.Load(writeWorkflow(t, t.TempDir(), "workflow.yaml", content)); err != nil {
+		t.Fatal(err)
+	}
+	return rec, w.Execute()
+}
+
+// TestFailedTaskVariables tests that failed_task variables describe the task that failed, not a background
+// task that was cancelled after it
+func TestFailedTaskVariables(t *testing.T) {
+	previous := shared.SwapVars(nil)
+	defer shared.SwapVars(previous)
+
+	rec := &recorder{}
+	w := New(WithCallback(rec))
+	err := w.Load(writeWorkflow(t, t.TempDir(), "workflow.yaml", `
+tasks:
+  - name: background
+    task: test_task
+    async: true
+    wait_cancel: true
+  - name: broken
+    task: test_task
+    fail: true
+    msg: broken on purpose
+on_failure:
+  - name: report
+    task: test_task
+    msg: "{{failed_task_name}}: {{failed_task_msg}}"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Execute() {
+		t.Fatal("expected the workflow to fail")
+	}
+
+	if r := rec.result(t, "background"); !r.Cancelled {
+		t.Errorf("background task not cancelled: %+v", r)
+	}
+	if msg := rec.result(t, "report").Msg; msg != "broken: broken on purpose" {
+		t.Errorf("on_failure saw %q", msg)
+	}
+}
+
+// TestOnSuccessBackgroundCancelled tests that background tasks of a failed on_success list are cancelled
+// and reported before the workflow ends
+func TestOnSuccessBackgroundCancelled(t *testing.T) {
+	rec, ok := runWorkflow(t, `
+tasks:
+  - name: first
+    task: test_task
+on_success:
+  - name: background
+    task: test_task
+    async: true
+    wait_cancel: true
+  - name: broken
+    task: test_task
+    fail: true
+`)
+	if ok {
+		t.Fatal("expected the workflow to fail")
+	}
+	if r := rec.result(t, "background"); !r.Cancelled {
+		t.Errorf("background task not cancelled: %+v", r)
+	}
+}
+
+// TestCancelledTaskWaitedFor tests that a cancelled background task that does not stop is waited for, with
+// its own result, and that the workflow holds its lock until the task has finished
+func TestCancelledTaskWaitedFor(t *testing.T) {
+	defer func(grace time.Duration) { cancelGrace = grace }(cancelGrace)
+	cancelGrace = 10 * time.Millisecond
+
+	gate := make(chan struct{})
+	testGates.Store("slow", gate)
+	defer testGates.Delete("slow")
+
+	dir := t.TempDir()
+	held := make(chan bool, 1)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		_, err := os.Stat(filepath.Join(dir, "opsblade-async-test.lock"))
+		held <- err == nil
+		close(gate)
+	}()
+
+	rec, ok := runWorkflow(t, `
+lock:
+  name: async-test
+  dir: `+dir+`
+tasks:
+  - name: slow
+    task: test_task
+    async: true
+    block: slow
+    msg: finished
+  - name: broken
+    task: test_task
+    fail: true
+`)
+	if ok {
+		t.Fatal("expected the workflow to fail")
+	}
+
+	r := rec.result(t, "slow")
+	if !r.Cancelled || !r.Success || r.Msg != "finished" {
+		t.Errorf("expected the task's own result, marked as cancelled: %+v", r)
+	}
+	if !<-held {
+		t.Error("the lock was released while the cancelled task was running")
+	}
+	if rec.index("event:async_wait") < 0 {
+		t.Errorf("no async_wait event: %v", rec.log)
+	}
+	if stop, released := rec.index("stop:slow"), rec.index("event:lock_released"); stop < 0 || released < stop {
+		t.Errorf("the lock was released before the task finished: %v", rec.log)
+	}
+	if !strings.Contains(strings.Join(rec.log, " "), "stop:broken") {
+		t.Errorf("no result of the failed task: %v", rec.log)
+	}
+}
+
+// TestLoopResult tests that ignore_errors and notify apply to a looped task as a whole, that changed_when
+// and failed_when apply to its items, and that its start shows the environment
+func TestLoopResult(t *testing.T) {
+	rec, ok := runWorkflow(t, `
+environments:
+  dev: {}
+tasks:
+  - name: gated
+    task: test_task
+    loop: [a, b]
+    serial: 1
+    ignore_errors: true
+    between_batches:
+      - name: gate
+        task: test_task
+        fail: true
+  - name: quiet
+    task: test_task
+    loop: [a]
+    changed: true
+    changed_when: false
+    notify: quiet handler
+  - name: noisy
+    task: test_task
+    loop: [a, b]
+    changed: true
+    notify: noisy handler
+handlers:
+  - name: quiet handler
+    task: test_task
+  - name: noisy handler
+    task: test_task
+`, WithEnvironment("dev"))
+	if !ok {
+		t.Fatalf("expected the aborted loop to be ignored: %v", rec.log)
+	}
+	if r := rec.result(t, "gated"); r.Success || !r.Ignored {
+		t.Errorf("expected an ignored failure: %+v", r)
+	}
+	if rec.result(t, "quiet").Changed || !rec.result(t, "noisy").Changed {
+		t.Errorf("unexpected changed status: %v", rec.log)
+	}
+	if rec.index("stop:quiet handler") >= 0 || rec.index("stop:noisy handler") < 0 {
+		t.Errorf("unexpected handlers: %v", rec.log)
+	}
+	for _, info := range rec.starts {
+		if info.Environment != "dev" {
+			t.Errorf("start of %s without the environment", info.Name)
+		}
+	}
+
+	rec, ok = runWorkflow(t, `
+tasks:
+  - name: checked
+    task: test_task
+    loop: [a]
+    data:
+      status: bad
+    failed_when:
+      field: status
+      compare: equal
+      value: bad
+  - name: after
+    task: test_task
+`)
+	if ok || rec.index("stop:after") >= 0 {
+		t.Errorf("expected failed_when to fail the loop: %v", rec.log)
+	}
+}
+
+// continuing is a recorder whose OnStop always returns true
+type continuing struct {
+	recorder
+}
+
+func (r *continuing) OnStop(result shared.TaskResult) bool {
+	r.recorder.OnStop(result)
+	return true
+}
+
+// stopping is a recorder whose OnStop returns false after the task named stop
+type stopping struct {
+	recorder
+	stop string
+}
+
+func (r *stopping) OnStop(result shared.TaskResult) bool {
+	return r.recorder.OnStop(result) && result.Name != r.stop
+}
+
+// TestIgnoredFailureWithCallback tests that ignored failures are ignored in workflows with a callback and in
+// child workflows, and that a failure stops the workflow even if the callback would continue
+func TestIgnoredFailureWithCallback(t *testing.T) {
+	previous := shared.SwapVars(nil)
+	defer shared.SwapVars(previous)
+
+	dir := t.TempDir()
+	writeWorkflow(t, dir, "child.yaml", `
+tasks:
+  - name: child ignored
+    task: test_task
+    fail: true
+    ignore_errors: true
+  - name: child after
+    task: test_task
+`)
+	rec := &continuing{}
+	w := New(WithCallback(rec))
+	err := w.Load(writeWorkflow(t, dir, "parent.yaml", `
+tasks:
+  - name: ignored
+    task: test_task
+    fail: true
+    ignore_errors: true
+  - name: child
+    task: workflow_run
+    file: child.yaml
+  - name: broken
+    task: test_task
+    fail: true
+  - name: after
+    task: test_task
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Execute() {
+		t.Fatal("expected the workflow to fail")
+	}
+	for _, name := range []string{"child after", "child", "broken"} {
+		if rec.index("stop:"+name) < 0 {
+			t.Errorf("%s did not run: %v", name, rec.log)
+		}
+	}
+	if rec.index("stop:after") >= 0 {
+		t.Errorf("the workflow continued after a failure: %v", rec.log)
+	}
+}
+
+// TestCallbackStops tests that a callback can stop a workflow after a successful task, including from a
+// child workflow
+func TestCallbackStops(t *testing.T) {
+	previous := shared.SwapVars(nil)
+	defer shared.SwapVars(previous)
+
+	dir := t.TempDir()
+	writeWorkflow(t, dir, "child.yaml", `
+tasks:
+  - name: child stop
+    task: test_task
+  - name: child after
+    task: test_task
+`)
+	for _, stop := range []string{"stop", "child stop"} {
+		t.Run(stop, func(t *testing.T) {
+			rec := &stopping{stop: stop}
+			w := New(WithCallback(rec))
+			err := w.Load(writeWorkflow(t, dir, "parent.yaml", `
+tasks:
+  - name: stop
+    task: test_task
+  - name: child
+    task: workflow_run
+    file: child.yaml
+  - name: after
+    task: test_task
+`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w.Execute() {
+				t.Fatal("expected the callback to stop the workflow")
+			}
+			if r := rec.result(t, stop); !r.Success {
+				t.Errorf("the task the callback stopped after failed: %+v", r)
+			}
+			for _, name := range []string{"child after", "after"} {
+				if rec.index("start:"+name) >= 0 {
+					t.Errorf("%s ran after the callback stopped the workflow: %v", name, rec.log)
+				}
+			}
+		})
+	}
+}
+
+// TestHandlerNotifiesEarlierHandler tests that a handler notified by a handler declared after it runs in
+// the same flush, and that handlers notifying each other do not run forever
+func TestHandlerNotifiesEarlierHandler(t *testing.T) {
+	rec, ok := runWorkflow(t, `
+tasks:
+  - name: change
+    task: test_task
+    changed: true
+    notify: second
+  - name: flush
+    task: flush_handlers
+  - name: after flush
+    task: test_task
+handlers:
+  - name: first
+    task: test_task
+    changed: true
+    notify: second
+  - name: second
+    task: test_task
+    changed: true
+    notify: first
+`)
+	if !ok {
+		t.Fatalf("workflow failed: %v", rec.log)
+	}
+	second, first, after := rec.index("stop:second"), rec.index("stop:first"), rec.index("stop:after flush")
+	if second < 0 || first < second || after < first {
+		t.Errorf("first did not run in the same flush as second: %v", rec.log)
+	}
+	if r := rec.result(t, "flush"); r.Msg != "2 handler(s) run" {
+		t.Errorf("unexpected flush result: %s", r.Msg)
+	}
+}
+
+// TestAuditBackgroundTasks tests that mutating tasks run in the background at the same time are all recorded
+// in one unbroken chain
+func TestAuditBackgroundTasks(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.jsonl")
+	var content strings.Builder
+	content.WriteString("tasks:\n")
+	for i := range 10 {
+		fmt.Fprintf(&content, "  - name: change %d\n    task: test_change\n    changed: true\n    async: true\n", i)
+	}
+	content.WriteString("  - name: wait\n    task: await\n")
+
+	rec, ok := runWorkflow(t, content.String(), WithAuditLog(filename))
+	if !ok {
+		t.Fatalf("workflow failed: %v", rec.log)
+	}
+	count, _, err := audit.Verify(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 10 {
+		t.Errorf("got %d audit entries, want 10", count)
+	}
+}
+
+// TestLockLost tests that once the workflow's lock is lost, e.g. to --force-unlock and another run, the tasks
+// that change something fail and the others still run
+func TestLockLost(t *testing.T) {
+	gate := make(chan struct{})
+	testGates.Store("lost", gate)
+	defer testGates.Delete("lost")
+
+	dir := t.TempDir()
+	cfg := lock.Config{Name: "lost-test", Dir: dir}
+	rec := &recorder{}
+	go func() {
+		defer close(gate)
+		// Wait for the workflow to acquire the lock, then take it over
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+			if holder, err := lock.ForceUnlock(cfg); err != nil || holder != nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		other, err := lock.Acquire(cfg, "", nil, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer func() { _ = other.Release() }()
+		for deadline := time.Now().Add(5 * time.Second); rec.index("event:lock_lost") < 0 && time.Now().Before(deadline); {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}()
+
+	previous := shared.SwapVars(nil)
+	defer shared.SwapVars(previous)
+	w := New(WithCallback(rec))
+	if err := w.Load(writeWorkflow(t, t.TempDir(), "workflow.yaml", `
+lock:
+  name: lost-test
+  ttl: 1
+  dir: `+dir+`
+tasks:
+  - name: first change
+    task: test_change
+  - name: long
+    task: test_task
+    block: lost
+  - name: second change
+    task: test_change
+on_failure:
+  - name: report
+    task: test_task
+`)); err != nil {
+		t.Fatal(err)
+	}
+	if w.Execute() {
+		t.Fatal("expected the workflow to fail")
+	}
+
+	if r := rec.result(t, "first change"); !r.Success {
+		t.Errorf("the task before the lock was lost failed: %+v", r)
+	}
+	if r := rec.result(t, "second change"); r.Success || !strings.Contains(r.Msg, "lock lost-test was lost") {
+		t.Errorf("expected the task to fail as the lock was lost: %+v", r)
+	}
+	if r := rec.result(t, "report"); !r.Success {
+		t.Errorf("a task that does not change anything failed: %+v", r)
+	}
+	if lost, long := rec.index("event:lock_lost"), rec.index("stop:long"); lost < 0 || long < lost {
+		t.Errorf("the lock was not lost while the task ran: %v", rec.log)
+	}
+}
+
+// testSecrets is a secret provider that returns the value in its map
+type testSecrets map[string]string
+
+func (p testSecrets) Secret(name string) (string, error) {
+	value, ok := p[name]
+	if !ok {
+		return "", fmt.Errorf("secret %s not found", name)
+	}
+	return value, nil
+}
+
+// TestSecretsRedacted tests that a secret copied into a variable is redacted from the task information, the
+// results, and the variable file saved by variables_save
+func TestSecretsRedacted(t *testing.T) {
+	shared.RegisterSecretProvider("engine_test", testSecrets{"token": "wf-token-91b3c6"})
+	filename := filepath.Join(t.TempDir(), "vars.json")
+
+	rec, ok := runWorkflow(t, `
+secrets:
+  provider: engine_test
+tasks:
+  - name: set
+    task: variables_set
+    set:
+      - name: header
+        value: "Authorization: Bearer {{secret:token}}"
+  - name: use
+    task: test_task
+    msg: "sent {{header}}"
+    data:
+      header: "{{header}}"
+  - name: save
+    task: variables_save
+    filename: `+filename+`
+`)
+	if !ok {
+		t.Fatalf("workflow failed: %v", rec.log)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "wf-token-91b3c6") || !strings.Contains(string(data), "Bearer "+shared.Redacted) {
+		t.Errorf("the saved variable file is not redacted: %s", data)
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	output, err := json.Marshal(map[string]any{"starts": rec.starts, "results": rec.results, "events": rec.events})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(output), "wf-token-91b3c6") {
+		t.Errorf("the secret appears in the output: %s", output)
+	}
+	if !strings.Contains(string(output), "sent Authorization: Bearer "+shared.Redacted) {
+		t.Errorf("the message was not redacted in place: %s", output)
+	}
+}
+
+// TestEnvSecretPerTask tests that a task with env: dev.env resolves env secrets from its own file, not from
+// the prod.env of an earlier task, and that tasks without env use the workflow's
+func TestEnvSecretPerTask(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkflow(t, dir, "prod.env", "ENGINE_TEST_KEY=prod-key-62ad0e\n")
+	writeWorkflow(t, dir, "dev.env", "ENGINE_TEST_KEY=dev-key-62ad0e\n")
+
+	previous := shared.SwapVars(nil)
+	defer shared.SwapVars(previous)
+	rec := &recorder{}
+	w := New(WithCallback(rec))
+	if err := w.Load(writeWorkflow(t, dir, "workflow.yaml", `
+env: `+filepath.Join(dir, "prod.env")+`
+tasks:
+  - name: prod
+    task: variables_set
+    set:
+      - name: prod_key
+        value: "{{secret:env:ENGINE_TEST_KEY}}"
+  - name: dev
+    task: variables_set
+    env: `+filepath.Join(dir, "dev.env")+`
+    set:
+      - name: dev_key
+        value: "{{secret:env:ENGINE_TEST_KEY}}"
+`)); err != nil {
+		t.Fatal(err)
+	}
+	if !w.Execute() {
+		t.Fatalf("workflow failed: %v", rec.log)
+	}
+	if got := shared.GetVar("prod_key"); got != "prod-key-62ad0e" {
+		t.Errorf("prod_key is %v", got)
+	}
+	if got := shared.GetVar("dev_key"); got != "dev-key-62ad0e" {
+		t.Errorf("dev_key is %v, the key of prod.env was reused", got)
+	}
+}
+
+// TestReadOnlyChildWorkflow tests that a read-only workflow is refused before any task runs if a child
+// workflow it runs, directly or through another child, changes something
+func TestReadOnlyChildWorkflow(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string // Error that refuses the workflow, empty if it is run
+	}{
+		{"read-only child", map[string]string{
+			"child.yaml": "tasks:\n  - name: look\n    task: test_task\n",
+		}, ""},
+		{"mutating child", map[string]string{
+			"child.yaml": "tasks:\n  - name: look\n    task: test_task\n  - name: change\n    task: test_change\n",
+		}, "child.yaml: tasks 2: test_change changes something"},
+		{"mutating grandchild in a handler", map[string]string{
+			"child.yaml":      "tasks:\n  - name: grandchild\n    task: workflow_run\n    file: grandchild.yaml\n",
+			"grandchild.yaml": "handlers:\n  - name: change\n    task: test_change\n",
+		}, "grandchild.yaml: handlers 1: test_change changes something"},
+		{"missing child", map[string]string{}, "unable to load workflow"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				writeWorkflow(t, dir, name, content)
+			}
+			previous := shared.SwapVars(nil)
+			defer shared.SwapVars(previous)
+			rec := &recorder{}
+			w := New(WithCallback(rec))
+			if err := w.Load(writeWorkflow(t, dir, "workflow.yaml", `
+read_only: true
+tasks:
+  - name: look
+    task: test_task
+  - name: child
+    task: workflow_run
+    file: child.yaml
+`)); err != nil {
+				t.Fatal(err)
+			}
+			ok := w.Execute()
+
+			if tt.wantErr == "" {
+				if !ok {
+					t.Fatalf("workflow failed: %v", rec.log)
+				}
+				return
+			}
+			if ok {
+				t.Fatal("expected the workflow to be refused")
+			}
+			var errs []string
+			for _, e := range rec.events {
+				if e.MessageType == "workflow_error" {
+					errs = append(errs, e.Msg)
+				}
+			}
+			if !strings.Contains(strings.Join(errs, "\n"), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, errs)
+			}
+			if rec.index("start:look") >= 0 {
+				t.Errorf("a task ran before the workflow was refused: %v", rec.log)
+			}
+		})
+	}
+
+	// A child that runs its parent is refused when it runs, not checked forever
+	rec, ok := runWorkflow(t, `
+read_only: true
+tasks:
+  - name: self
+    task: workflow_run
+    file: workflow.yaml
+`)
+	if r := rec.result(t, "self"); ok || !strings.Contains(r.Msg, "runs itself") {
+		t.Errorf("expected the workflow to refuse to run itself: %+v", r)
+	}
+}
+
+// TestPolicyChildWorkflow tests that a child workflow inherits the policy and tags of its parent, and that a
+// task that violates the policy is refused and reported as a policy_violation event
+func TestPolicyChildWorkflow(t *testing.T) {
+	dir := t.TempDir()
+	p, err := policy.Load(writeWorkflow(t, dir, "policy.yaml", `
+rules:
+  - name: no-change-in-prod
+    description: nothing may be changed in prod
+    tasks: [test_change]
+    when:
+      - field: workflow.tags
+        compare: contains
+        value: prod
+    deny: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	child := writeWorkflow(t, dir, "child.yaml", `
+tasks:
+  - name: child look
+    task: test_task
+  - name: child change
+    task: test_change
+    msg: changed
+    changed: true
+`)
+
+	// The child workflow is not tagged, so on its own it may change something
+	previous := shared.SwapVars(nil)
+	defer shared.SwapVars(previous)
+	rec := &recorder{}
+	w := New(WithCallback(rec), WithPolicy(p))
+	if err = w.Load(child); err != nil {
+		t.Fatal(err)
+	}
+	if !w.Execute() || !rec.result(t, "child change").Changed {
+		t.Fatalf("the untagged workflow failed: %v", rec.log)
+	}
+
+	rec, ok := runWorkflow(t, `
+tags: [prod]
+tasks:
+  - name: look
+    task: test_task
+  - name: child
+    task: workflow_run
+    file: `+child+`
+`, WithPolicy(p))
+	if ok {
+		t.Fatal("expected the workflow to fail")
+	}
+	if r := rec.result(t, "child look"); !r.Success {
+		t.Errorf("a task that does not violate the policy failed: %+v", r)
+	}
+	r := rec.result(t, "child change")
+	if r.Success || r.Changed || !strings.Contains(r.Msg, "Task refused: violates policy rule no-change-in-prod") {
+		t.Errorf("expected the task to be refused: %+v", r)
+	}
+
+	var violations []shared.WorkflowEvent
+	for _, e := range rec.events {
+		if e.MessageType == "policy_violation" {
+			violations = append(violations, e)
+		}
+	}
+	if len(violations) != 1 {
+		t.Fatalf("expected one policy_violation event, got %d: %v", len(violations), rec.log)
+	}
+	data := violations[0].Data
+	if data["rule"] != "no-change-in-prod" || data["task"] != "test_change" || data["name"] != "child change" ||
+		data["description"] != "nothing may be changed in prod" {
+		t.Errorf("unexpected policy_violation event: %+v", violations[0])
+	}
+}
+
+// TestTaskDuration tests that the duration of a task covers its execution, whether it is run directly, in the
+// background, or as an item of a loop
+func TestTaskDuration(t *testing.T) {
+	rec, ok := runWorkflow(t, `
+tasks:
+  - name: direct
+    task: test_task
+    sleep: 200
+  - name: background
+    task: test_task
+    sleep: 200
+    async: true
+  - name: loop
+    task: test_task
+    sleep: 100
+    loop: [1, 2]
+  - name: wait
+    task: await
+`)
+	if !ok {
+		t.Fatalf("workflow failed: %v", rec.log)
+	}
+	for name, want := range map[string]time.Duration{"direct": 200, "background": 200, "loop": 200} {
+		r := rec.result(t, name)
+		if d := r.Duration(); d < want*time.Millisecond {
+			t.Errorf("%s: duration %s, want at least %dms", name, d, want)
+		}
+	}
+}
+
+// TestChildWorkflowBackgroundTasks tests that a child workflow is refused while background tasks of the parent
+// are running, as they would see the child's variables, and is run once they are awaited
+func TestChildWorkflowBackgroundTasks(t *testing.T) {
+	dir := t.TempDir()
+	child := writeWorkflow(t, dir, "child.yaml", "tasks:\n  - name: child look\n    task: test_task\n")
+
+	rec, ok := runWorkflow(t, `
+tasks:
+  - name: background
+    task: test_task
+    async: true
+    wait_cancel: true
+  - name: child
+    task: workflow_run
+    file: `+child+`
+`)
+	if ok {
+		t.Fatal("expected the workflow to fail")
+	}
+	if r := rec.result(t, "child"); !strings.Contains(r.Msg, "background tasks are running, await them first: background") {
+		t.Errorf("expected the child workflow to be refused: %+v", r)
+	}
+	if rec.index("start:child look") >= 0 {
+		t.Errorf("the child workflow ran: %v", rec.log)
+	}
+	if r := rec.result(t, "background"); !r.Cancelled {
+		t.Errorf("expected the background task to be cancelled: %+v", r)
+	}
+
+	rec, ok = runWorkflow(t, `
+tasks:
+  - name: background
+    task: test_task
+    async: true
+  - name: wait
+    task: await
+  - name: child
+    task: workflow_run
+    file: `+child+`
+`)
+	if !ok || !rec.result(t, "child look").Success {
+		t.Errorf("the child workflow failed after the background tasks were awaited: %v", rec.log)
+	}
+}
+
+// TestLoopToleratedFailures tests that failed items are reported as ignored, so that the recap agrees with the
+// loop's result, and that a loop aborted by its failures is reported as the failure
+func TestLoopToleratedFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		items     string
+		wantOK    bool
+		wantRecap string
+	}{
+		{"tolerated", "[good, bad, good, good]", true, "failed=0 ignored=1"},
+		{"exceeded", "[bad, bad, good, good]", false, "failed=1 ignored=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, ok := runWorkflow(t, `
+tasks:
+  - name: rolling
+    task: test_task
+    loop: `+tt.items+`
+    serial: 2
+    max_fail_percentage: 50
+    data:
+      status: "{{item}}"
+    failed_when:
+      field: status
+      compare: equal
+      value: bad
+`)
+			if ok != tt.wantOK {
+				t.Fatalf("workflow result %t, want %t: %v", ok, tt.wantOK, rec.log)
+			}
+			var recap shared.WorkflowEvent
+			for _, e := range rec.events {
+				if e.MessageType == "workflow_recap" {
+					recap = e
+				}
+			}
+			if !strings.Contains(recap.Msg, tt.wantRecap) {
+				t.Errorf("recap %q, want %q", recap.Msg, tt.wantRecap)
+			}
+			if r := rec.result(t, "rolling"); r.Success != tt.wantOK || r.Ignored {
+				t.Errorf("unexpected loop result: %+v", r)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package workflow
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// defaultDryRunMaxAge is how long a dry run allows real runs of an environment that requires one, in seconds
+const defaultDryRunMaxAge = 24 * 60 * 60
+
+// Environment contains the settings of a named environment, selected with WithEnvironment
+type Environment struct {
+	Env                string         `yaml:"env"`                  // Environment file, replaces the file level env
+	AWSProfile         string         `yaml:"aws_profile"`          // Profile of AWS tasks that do not set one
+	AWSRegion          string         `yaml:"aws_region"`           // Region of AWS tasks that do not set one
+	Variables          map[string]any `yaml:"variables"`            // Variables set before the first task
+	ReadOnly           bool           `yaml:"read_only"`            // Refuse tasks that change something
+	RequireDryRunFirst bool           `yaml:"require_dryrun_first"` // Refuse real runs without a recent dry run
+	DryRunMaxAge       int            `yaml:"dryrun_max_age"`       // Seconds a dry run allows real runs, 24 hours by default
+}
+
+// dryRunRecord is written after a successful dry run of an environment that requires one
+type dryRunRecord struct {
+	Workflow    string    `json:"workflow"`
+	Environment string    `json:"environment"`
+	Hash        string    `json:"hash"`
+	RunID       string    `json:"run_id"`
+	User        string    `json:"user"`
+	Time        time.Time `json:"time"`
+}
+
+// selectEnvironment applies the settings of the selected environment and sets its variables. A workflow that
+// defines environments requires one to be selected. A child workflow that does not define environments uses
+// the environment of its parent.
+func (w *Workflow) selectEnvironment() error {
+	w.selected = nil
+	names := slices.Sorted(maps.Keys(w.Environments))
+	if w.environment == "" {
+		if len(names) > 0 {
+			return fmt.Errorf("the workflow defines environments %s, select one with --environment", strings.Join(names, ", "))
+		}
+		return nil
+	}
+
+	settings, ok := w.Environments[w.environment]
+	switch {
+	case ok:
+	case len(names) == 0 && w.parentEnvironment != nil:
+		// The environment file of the parent is already the default of the child, and does not replace
+		// the env setting of the child
+		inherited := *w.parentEnvironment
+		inherited.Env = ""
+		settings = &inherited
+	case len(names) == 0:
+		return fmt.Errorf("environment %s is selected, but the workflow does not define environments", w.environment)
+	default:
+		return fmt.Errorf("environment %s is not defined, the workflow defines %s", w.environment, strings.Join(names, ", "))
+	}
+	if settings == nil {
+		settings = &Environment{}
+	}
+	if settings.DryRunMaxAge < 0 {
+		return fmt.Errorf("environment %s: dryrun_max_age must not be negative", w.environment)
+	}
+	w.selected = settings
+
+	for name, value := range settings.Variables {
+		shared.SetVar(name, value)
+	}
+	shared.SetVar("environment", w.environment)
+	return nil
+}
+
+// envFile returns the environment file of tasks that do not set their own
+func (w *Workflow) envFile() string {
+	if w.selected != nil && w.selected.Env != "" {
+		return w.selected.Env
+	}
+	return w.Env
+}
+
+// requiresDryRun returns true if the selected environment requires a dry run before a real run. A child
+// workflow is covered by the dry run of the workflow that runs it, so it is not checked separately.
+func (w *Workflow) requiresDryRun() bool {
+	return w.selected != nil && w.selected.RequireDryRunFirst && len(w.ancestors) == 0
+}
+
+// isDryRun returns true if the run does not change anything
+func (w *Workflow) isDryRun() bool {
+	return w.DryRun || w.planMode
+}
+
+// dryRunFile returns the file that records a dry run of the workflow in the selected environment. The name is
+// derived from the content of the workflow, so a change to the workflow requires another dry run.
+func (w *Workflow) dryRunFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	sum := sha256.Sum256([]byte(w.digest + "\x00" + w.environment))
+	return filepath.Join(dir, "opsblade", "dryruns", hex.EncodeToString(sum[:16])+".json")
+}
+
+// checkDryRun refuses a real run of an environment that requires a dry run, unless the same workflow had a
+// successful dry run in the environment within its dryrun_max_age
+func (w *Workflow) checkDryRun() error {
+	if !w.requiresDryRun() || w.isDryRun() {
+		return nil
+	}
+
+	refuse := func(reason string) error {
+		return fmt.Errorf("environment %s requires a successful dry run of this workflow first (%s), run it with --dryrun or --plan",
+			w.environment, reason)
+	}
+	data, err := os.ReadFile(w.dryRunFile())
+	if errors.Is(err, fs.ErrNotExist) {
+		return refuse("no dry run of this version of the workflow was found")
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read dry run record: %w", err)
+	}
+	var record dryRunRecord
+	if err = json.Unmarshal(data, &record); err != nil || record.Hash != w.digest || record.Environment != w.environment {
+		return refuse("the dry run record is invalid")
+	}
+
+	maxAge := time.Duration(w.selected.DryRunMaxAge) * time.Second
+	if maxAge == 0 {
+		maxAge = defaultDryRunMaxAge * time.Second
+	}
+	if age := time.Since(record.Time); age > maxAge {
+		return refuse(fmt.Sprintf("the last dry run was %s ago", age.Round(time.Minute)))
+	}
+	return nil
+}
+
+// recordDryRun records a successful dry run of an environment that requires one
+func (w *Workflow) recordDryRun() error {
+	if !w.requiresDryRun() || !w.isDryRun() {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(dryRunRecord{
+		Workflow:    w.Summary().Name,
+		Environment: w.environment,
+		Hash:        w.digest,
+		RunID:       w.runID,
+		User:        shared.CurrentUser(),
+		Time:        time.Now().UTC(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	filename := w.dryRunFile()
+	if err = os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return fmt.Errorf("unable to record the dry run: %w", err)
+	}
+	if err = shared.WriteFileAtomic(filename, data, 0600); err != nil {
+		return fmt.Errorf("unable to record the dry run: %w", err)
+	}
+	return nil
+}
@@ -64,6 +64,9 @@ type Task struct {
 // method) can be returned. Note that struct returned by the constructor function is the struct defined above,
 // not to be confused with the shared.Task interface. In practical terms, copy this init() function, use Task as your
 // struct name, and change the task ID to something unique.
+
+// Tasks that change something (start an instance, create an issue, etc.) must pass shared.Mutating() as a third
+// argument so that they are refused in read-only mode. This task only reads, so it is not marked.
 func init() {
 	shared.RegisterTask("example", func(context shared.TaskContext) shared.Task {
 		return &Task{Context: context}
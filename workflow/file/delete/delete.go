@@ -5,7 +5,9 @@ package delete
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 
 	"github.com/OpsBlade/OpsBlade/shared"
@@ -19,7 +21,7 @@ type Task struct {
 func init() {
 	shared.RegisterTask("file_delete", func(context shared.TaskContext) shared.Task {
 		return &Task{Context: context}
-	})
+	}, shared.Mutating())
 }
 
 func (t *Task) Execute() shared.TaskResult {
@@ -36,8 +38,13 @@ func (t *Task) Execute() shared.TaskResult {
 		return t.Context.Error("unable to delete, filename is empty", nil)
 	}
 
+	// Deleting a file that does not exist is a no-op
+	if _, err = os.Stat(t.FileName); errors.Is(err, fs.ErrNotExist) {
+		return t.Context.Result(true, fmt.Sprintf("File %s does not exist, nothing to delete", t.FileName), nil)
+	}
+
 	if t.Context.DryRun {
-		return t.Context.Result(true, fmt.Sprintf("Dry run: would delete file: %s", t.FileName), nil)
+		return t.Context.DryRunResult(fmt.Sprintf("delete file %s", t.FileName), nil)
 	}
 
 	err = os.Remove(t.FileName)
@@ -45,5 +52,32 @@ func (t *Task) Execute() shared.TaskResult {
 		return t.Context.Error("failed delete file", err)
 	}
 
-	return t.Context.Result(true, fmt.Sprintf("Deleted %s", t.FileName), nil)
+	r := t.Context.Result(true, fmt.Sprintf("Deleted %s", t.FileName), nil)
+	r.Changed = true
+	return r
+}
+
+// Plan describes the change Execute would make, without making it
+func (t *Task) Plan() shared.TaskResult {
+	if err := json.Unmarshal(t.Context.Instructions, t); err != nil {
+		return t.Context.Error("failed to deserialize data", err)
+	}
+
+	shared.ProcessVars(t)
+
+	if t.FileName == "" {
+		return t.Context.Error("unable to delete, filename is empty", nil)
+	}
+
+	if _, err := os.Stat(t.FileName); errors.Is(err, fs.ErrNotExist) {
+		return t.Context.Planned(nil, nil)
+	}
+
+	return t.Context.Planned([]shared.PlannedChange{
+		{
+			Action:      "Remove",
+			Target:      t.FileName,
+			Description: "delete file",
+		},
+	}, nil)
 }
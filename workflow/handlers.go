@@ -0,0 +1,120 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// PhaseHandlers is the phase of handler tasks
+const PhaseHandlers = "handlers"
+
+// TaskFlushHandlers is an engine built-in task that runs any notified handlers immediately
+const TaskFlushHandlers = "flush_handlers"
+
+// notifyList returns the handler names in a task's notify field, which may be a string or a list of strings
+func notifyList(rawTask map[string]any) ([]string, error) {
+	switch v := rawTask["notify"].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []any:
+		names := make([]string, 0, len(v))
+		for _, item := range v {
+			name, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("notify must be a handler name or a list of handler names")
+			}
+			names = append(names, name)
+		}
+		return names, nil
+	case []string:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("notify must be a handler name or a list of handler names")
+	}
+}
+
+// validateHandlers checks that handlers have unique names and that every notify refers to a handler
+func (w *Workflow) validateHandlers() error {
+	handlers := make(map[string]bool)
+	for i, h := range w.Handlers {
+		name, _ := h["name"].(string)
+		if name == "" {
+			return fmt.Errorf("handler %d has no name", i+1)
+		}
+		if handlers[name] {
+			return fmt.Errorf("duplicate handler name: %s", name)
+		}
+		if task, _ := h["task"].(string); task == TaskFlushHandlers {
+			return fmt.Errorf("handler %s: %s cannot be used in a handler", name, TaskFlushHandlers)
+		}
+		handlers[name] = true
+	}
+
+	for _, list := range w.lists() {
+		for i, rawTask := range list.tasks {
+			names, err := notifyList(rawTask)
+			if err != nil {
+				return fmt.Errorf("%s %d: %w", list.name, i+1, err)
+			}
+			for _, name := range names {
+				if !handlers[name] {
+					return fmt.Errorf("%s %d: notify refers to unknown handler: %s", list.name, i+1, name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// notify records the handlers to be run if a task succeeded and changed something
+func (w *Workflow) notify(rawTask map[string]any, result shared.TaskResult) {
+	if !result.Success || !result.Changed {
+		return
+	}
+	names, _ := notifyList(rawTask)
+	for _, name := range names {
+		w.notified[name] = true
+	}
+}
+
+// flushHandlers runs all notified handlers once, in declaration order. A handler may notify another
+// handler, which then runs in the same flush: handlers notified during a pass over the list are run in
+// another pass, until no notified handler remains. A handler that is notified again after it has run in
+// the flush stays notified, and runs at the next flush. It returns the number of handlers run and false if
+// one of them failed.
+func (w *Workflow) flushHandlers() (int, bool) {
+	count := 0
+	ran := make(map[string]bool)
+	for pending := true; pending; {
+		pending = false
+		for i, rawTask := range w.Handlers {
+			name, _ := rawTask["name"].(string)
+			if !w.notified[name] || ran[name] {
+				continue
+			}
+			delete(w.notified, name)
+			ran[name] = true
+			pending = true
+			count++
+			if !w.taskEnd(rawTask, w.runTask(position{phase: PhaseHandlers}, i+1, rawTask)) {
+				return count, false
+			}
+		}
+	}
+	return count, true
+}
+
+// flushHandlersTask implements the flush_handlers built-in task
+func (w *Workflow) flushHandlersTask(_ position, _ map[string]any, taskContext shared.TaskContext) shared.TaskResult {
+	count, ok := w.flushHandlers()
+	if !ok {
+		return taskContext.Error("a handler failed", nil)
+	}
+	return taskContext.Result(true, fmt.Sprintf("%d handler(s) run", count), nil)
+}
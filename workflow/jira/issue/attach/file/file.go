@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/OpsBlade/OpsBlade/services/cloudjira"
@@ -23,7 +24,7 @@ type Task struct {
 func init() {
 	shared.RegisterTask("jira_issue_attach_file", func(context shared.TaskContext) shared.Task {
 		return &Task{Context: context}
-	})
+	}, shared.Mutating())
 }
 
 func (t *Task) Execute() shared.TaskResult {
@@ -43,7 +44,9 @@ func (t *Task) Execute() shared.TaskResult {
 		return t.Context.Error("issue_id and file_name are required", nil)
 	}
 
-	jiraClientConfig, err := cloudjira.New(cloudjira.WithEnvironment(shared.SelectEnv(t.Env, t.Context.Env)))
+	jiraClientConfig, err := cloudjira.New(
+		cloudjira.WithEnvironment(shared.SelectEnv(t.Env, t.Context.Env)),
+		cloudjira.WithTaskContext(&t.Context))
 	if err != nil {
 		return t.Context.Error("failed to create JIRA client", err)
 	}
@@ -69,6 +72,11 @@ func (t *Task) Execute() shared.TaskResult {
 		fileNameOnly = t.FileName[lastSlash+1:]
 	}
 
+	if t.Context.DryRun {
+		return t.Context.DryRunResult(fmt.Sprintf("attach %s to JIRA issue %s", fileNameOnly, t.IssueId),
+			map[string]any{"jira_attached_file_name": fileNameOnly})
+	}
+
 	// Add the file to the issue
 	_, _, err = client.Issue.PostAttachment(t.IssueId, file, fileNameOnly)
 	if err != nil {
@@ -78,3 +86,24 @@ func (t *Task) Execute() shared.TaskResult {
 	return t.Context.Result(true, fmt.Sprintf("file attached to JIRA issue %s", t.IssueId),
 		map[string]any{"jira_attached_file_name": fileNameOnly})
 }
+
+// Plan describes the change Execute would make, without making it
+func (t *Task) Plan() shared.TaskResult {
+	if err := json.Unmarshal(t.Context.Instructions, t); err != nil {
+		return t.Context.Error("failed to deserialize data", err)
+	}
+
+	shared.ProcessVars(t)
+
+	if t.IssueId == "" || t.FileName == "" {
+		return t.Context.Error("issue_id and file_name are required", nil)
+	}
+
+	return t.Context.Planned([]shared.PlannedChange{
+		{
+			Action:      "PostAttachment",
+			Target:      t.IssueId,
+			Description: fmt.Sprintf("attach %s to JIRA issue %s", t.FileName, t.IssueId),
+		},
+	}, map[string]any{"jira_attached_file_name": filepath.Base(t.FileName)})
+}
@@ -44,7 +44,9 @@ func (t *Task) Execute() shared.TaskResult {
 	data["check_jira_issue_required_status"] = t.RequiredStatus
 	data["check_jira_issue_required_resolution"] = t.RequiredResolution
 
-	jiraClientConfig, err := cloudjira.New(cloudjira.WithEnvironment(shared.SelectEnv(t.Env, t.Context.Env)))
+	jiraClientConfig, err := cloudjira.New(
+		cloudjira.WithEnvironment(shared.SelectEnv(t.Env, t.Context.Env)),
+		cloudjira.WithTaskContext(&t.Context))
 	if err != nil {
 		return t.Context.Error("failed to create JIRA client", err)
 	}
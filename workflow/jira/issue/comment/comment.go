@@ -23,7 +23,7 @@ type Task struct {
 func init() {
 	shared.RegisterTask("jira_issue_comment", func(context shared.TaskContext) shared.Task {
 		return &Task{Context: context}
-	})
+	}, shared.Mutating())
 }
 
 func (t *Task) Execute() shared.TaskResult {
@@ -43,7 +43,9 @@ func (t *Task) Execute() shared.TaskResult {
 		return t.Context.Error("issue_id and comment are required", nil)
 	}
 
-	jiraClientConfig, err := cloudjira.New(cloudjira.WithEnvironment(shared.SelectEnv(t.Env, t.Context.Env)))
+	jiraClientConfig, err := cloudjira.New(
+		cloudjira.WithEnvironment(shared.SelectEnv(t.Env, t.Context.Env)),
+		cloudjira.WithTaskContext(&t.Context))
 	if err != nil {
 		return t.Context.Error("failed to create JIRA client", err)
 	}
@@ -54,6 +56,10 @@ func (t *Task) Execute() shared.TaskResult {
 		return t.Context.Error("unable to create JIRA client", err)
 	}
 
+	if t.Context.DryRun {
+		return t.Context.DryRunResult(fmt.Sprintf("add a comment to JIRA issue %s", t.IssueId), nil)
+	}
+
 	// Create a jira comment object
 	comment := &jira.Comment{
 		Body: jiraClientConfig.ResolveTags(t.Comment),
@@ -67,3 +73,25 @@ func (t *Task) Execute() shared.TaskResult {
 
 	return t.Context.Result(true, fmt.Sprintf("comment added to JIRA issue %s", t.IssueId), nil)
 }
+
+// Plan describes the change Execute would make, without making it
+func (t *Task) Plan() shared.TaskResult {
+	if err := json.Unmarshal(t.Context.Instructions, t); err != nil {
+		return t.Context.Error("failed to deserialize data", err)
+	}
+
+	shared.ProcessVars(t)
+
+	if t.IssueId == "" || t.Comment == "" {
+		return t.Context.Error("issue_id and comment are required", nil)
+	}
+
+	return t.Context.Planned([]shared.PlannedChange{
+		{
+			Action:      "AddComment",
+			Target:      t.IssueId,
+			Description: fmt.Sprintf("add a comment to JIRA issue %s", t.IssueId),
+			Details:     map[string]any{"comment": t.Comment},
+		},
+	}, nil)
+}
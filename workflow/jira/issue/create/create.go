@@ -28,7 +28,7 @@ type Task struct {
 func init() {
 	shared.RegisterTask("jira_issue_create", func(context shared.TaskContext) shared.Task {
 		return &Task{Context: context}
-	})
+	}, shared.Mutating())
 }
 
 func (t *Task) Execute() shared.TaskResult {
@@ -46,7 +46,9 @@ func (t *Task) Execute() shared.TaskResult {
 		shared.DumpTask(t)
 	}
 
-	jiraClientConfig, err := cloudjira.New(cloudjira.WithEnvironment(shared.SelectEnv(t.Env, t.Context.Env)))
+	jiraClientConfig, err := cloudjira.New(
+		cloudjira.WithEnvironment(shared.SelectEnv(t.Env, t.Context.Env)),
+		cloudjira.WithTaskContext(&t.Context))
 	if err != nil {
 		return t.Context.Error("failed to create JIRA client", err)
 	}
@@ -59,7 +61,7 @@ func (t *Task) Execute() shared.TaskResult {
 		}
 
 		if t.Context.Debug {
-			fmt.Printf("Assignee '%s' resolved to AccountID %s\n", t.Assignee, userAccountID)
+			shared.Printf("Assignee '%s' resolved to AccountID %s\n", t.Assignee, userAccountID)
 		}
 	}
 
@@ -73,7 +75,7 @@ func (t *Task) Execute() shared.TaskResult {
 	var sprint jira.Sprint
 	var sprintField string
 	if t.ActiveSprint {
-		fmt.Println("Finding active sprint for project", t.Project)
+		shared.Println("Finding active sprint for project", t.Project)
 		sprint, err = jiraClientConfig.GetActiveSprint(t.Project)
 		if err != nil {
 			return t.Context.Error("failed to get active sprint", err)
@@ -116,43 +118,83 @@ func (t *Task) Execute() shared.TaskResult {
 	}
 
 	if t.Context.DryRun {
-		shared.SetVar("jira_issue_id", "jira-issue-dry-run")
-	} else {
-		createdIssue, response, issueErr := client.Issue.Create(&jiraIssue)
-		if issueErr != nil {
-			if t.Context.Debug {
-				fmt.Println("Error creating issue. Jira response:", jiraClientConfig.ResponseToString(response))
-				fmt.Println(shared.Dump(jiraIssue))
-			}
-			return t.Context.Error("failed to create JIRA issue", issueErr)
+		data["jira_issue_id"] = shared.Placeholder("jira_issue_id")
+		data["jira_project"] = t.Project
+		if t.Assignee != "" {
+			data["jira_assignee"] = t.Assignee
+			data["jira_assignee_account_id"] = userAccountID
 		}
+		return t.Context.DryRunResult(fmt.Sprintf("create %s issue in project %s: %s", t.IssueType, t.Project, t.Summary), data)
+	}
 
+	createdIssue, response, issueErr := client.Issue.Create(&jiraIssue)
+	if issueErr != nil {
 		if t.Context.Debug {
-			fmt.Printf("Created JIRA issue '%s'\n", createdIssue.Key)
+			shared.Println("Error creating issue. Jira response:", jiraClientConfig.ResponseToString(response))
+			shared.Println(shared.Dump(jiraIssue))
 		}
+		return t.Context.Error("failed to create JIRA issue", issueErr)
+	}
 
-		// Save the created issue ID
-		//shared.SetVar("jira_issue_id", createdIssue.Key)
-		data["jira_issue_id"] = createdIssue.Key
-		data["jira_project"] = t.Project
+	if t.Context.Debug {
+		shared.Printf("Created JIRA issue '%s'\n", createdIssue.Key)
+	}
 
-		// If an assignee is provided, assign the issue
-		if t.Assignee != "" {
-			_, assignErr := client.Issue.UpdateAssignee(createdIssue.ID, &jira.User{
-				AccountID: userAccountID,
-			})
-
-			if assignErr != nil {
-				return t.Context.Error("failed to update JIRA user assignee", assignErr)
-			}
-
-			if t.Context.Debug {
-				fmt.Printf("Assigned JIRA issue '%s' to user '%s' (%s)\n",
-					createdIssue.Key, t.Assignee, userAccountID)
-			}
-			data["jira_assignee"] = t.Assignee
-			data["jira_assignee_account_id"] = userAccountID
+	// Save the created issue ID
+	//shared.SetVar("jira_issue_id", createdIssue.Key)
+	data["jira_issue_id"] = createdIssue.Key
+	data["jira_project"] = t.Project
+
+	// If an assignee is provided, assign the issue
+	if t.Assignee != "" {
+		_, assignErr := client.Issue.UpdateAssignee(createdIssue.ID, &jira.User{
+			AccountID: userAccountID,
+		})
+
+		if assignErr != nil {
+			return t.Context.Error("failed to update JIRA user assignee", assignErr)
+		}
+
+		if t.Context.Debug {
+			shared.Printf("Assigned JIRA issue '%s' to user '%s' (%s)\n",
+				createdIssue.Key, t.Assignee, userAccountID)
 		}
+		data["jira_assignee"] = t.Assignee
+		data["jira_assignee_account_id"] = userAccountID
+	}
+	r := t.Context.Result(true, fmt.Sprintf("JIRA issue %s created", createdIssue.Key), data)
+	r.Changed = true
+	return r
+}
+
+// Plan describes the change Execute would make, without making it
+func (t *Task) Plan() shared.TaskResult {
+	if err := json.Unmarshal(t.Context.Instructions, t); err != nil {
+		return t.Context.Error("failed to deserialize data", err)
+	}
+
+	shared.ProcessVars(t)
+
+	description := fmt.Sprintf("create %s issue in project %s: %s", t.IssueType, t.Project, t.Summary)
+	if t.ActiveSprint {
+		description += " (in the active sprint)"
+	}
+	if t.Assignee != "" {
+		description += fmt.Sprintf(", assigned to %s", t.Assignee)
 	}
-	return t.Context.Result(true, fmt.Sprintf("JIRA issue %s created", data), data)
+
+	return t.Context.Planned([]shared.PlannedChange{
+		{
+			Action:      "CreateIssue",
+			Target:      t.Project,
+			Description: description,
+			Details: map[string]any{
+				"issue_type":    t.IssueType,
+				"summary":       t.Summary,
+				"description":   t.Description,
+				"assignee":      t.Assignee,
+				"active_sprint": t.ActiveSprint,
+			},
+		},
+	}, map[string]string{"jira_issue_id": shared.Placeholder("jira_issue_id"), "jira_project": t.Project})
 }
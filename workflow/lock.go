@@ -0,0 +1,118 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package workflow
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/OpsBlade/OpsBlade/lock"
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// lockConfig returns the lock settings of the workflow. The lock name defaults to the workflow name, or the
+// name of the workflow file, and a relative lock directory is relative to the directory of the workflow file.
+func (w *Workflow) lockConfig() lock.Config {
+	cfg := *w.Lock
+	if cfg.Name == "" {
+		cfg.Name = w.Name
+	}
+	if cfg.Name == "" && w.source != "" {
+		cfg.Name = strings.TrimSuffix(filepath.Base(w.source), filepath.Ext(w.source))
+	}
+	if cfg.Dir != "" && !filepath.IsAbs(cfg.Dir) && w.source != "" {
+		cfg.Dir = filepath.Join(filepath.Dir(w.source), cfg.Dir)
+	}
+	return cfg
+}
+
+// validateLock checks the lock settings, if any
+func (w *Workflow) validateLock() error {
+	if w.Lock == nil {
+		return nil
+	}
+	if err := w.lockConfig().Validate(); err != nil {
+		return fmt.Errorf("lock: %w", err)
+	}
+	return nil
+}
+
+// acquireLock acquires the workflow's lock, if it has one, and returns a function that releases it. Dry runs
+// and plans do not change anything, so they do not take the lock. A child workflow with the lock of a
+// parent already holds it.
+func (w *Workflow) acquireLock() (func(), error) {
+	if w.Lock == nil || w.DryRun || w.planMode {
+		return func() {}, nil
+	}
+	cfg := w.lockConfig()
+	if w.heldLocks[cfg.Name] != nil {
+		return func() {}, nil
+	}
+
+	waiting := func(holder *lock.Holder) {
+		w.progress(shared.WorkflowEvent{
+			MessageType: "lock_wait",
+			Msg:         fmt.Sprintf("waiting up to %d seconds for lock %s held by %s", cfg.Wait, cfg.Name, holder.String()),
+			Data:        map[string]any{"lock": cfg.Name, "holder": holder},
+		})
+	}
+	// The lock is lost in the background, so the tasks that change something check the flag before they run
+	lostFlag := &atomic.Bool{}
+	lost := func(err error) {
+		lostFlag.Store(true)
+		w.progress(shared.WorkflowEvent{MessageType: "lock_lost", Msg: err.Error() + ", tasks that change something will fail",
+			Data: map[string]any{"lock": cfg.Name}})
+	}
+	l, err := lock.Acquire(cfg, w.source, waiting, lost)
+	if err != nil {
+		return nil, err
+	}
+	w.event(shared.WorkflowEvent{MessageType: "lock_acquired", Msg: fmt.Sprintf("acquired lock %s", cfg.Name),
+		Data: map[string]any{"lock": cfg.Name}})
+
+	if w.heldLocks == nil {
+		w.heldLocks = make(map[string]*atomic.Bool)
+	}
+	w.heldLocks[cfg.Name] = lostFlag
+	return func() {
+		delete(w.heldLocks, cfg.Name)
+		if err := l.Release(); err != nil {
+			w.workflowError(fmt.Errorf("unable to release lock %s: %w", cfg.Name, err))
+			return
+		}
+		w.event(shared.WorkflowEvent{MessageType: "lock_released", Msg: fmt.Sprintf("released lock %s", cfg.Name),
+			Data: map[string]any{"lock": cfg.Name}})
+	}, nil
+}
+
+// lostLock returns the name of a lock held by the workflow or its parents that was lost, if any. Another run
+// may hold the lock by now, so tasks that change something must not be run.
+func (w *Workflow) lostLock() string {
+	for _, name := range slices.Sorted(maps.Keys(w.heldLocks)) {
+		if w.heldLocks[name].Load() {
+			return name
+		}
+	}
+	return ""
+}
+
+// inheritLocks passes the locks held by the workflow and its parents to a child workflow
+func (w *Workflow) inheritLocks(child *Workflow) {
+	child.heldLocks = maps.Clone(w.heldLocks)
+}
+
+// ForceUnlock releases the lock of the loaded workflow regardless of its holder, e.g. after a run was
+// interrupted in a way that left the lock behind. It returns the holder, or nil if the lock was not held.
+//
+//goland:noinspection GoUnusedExportedFunction
+func (w *Workflow) ForceUnlock() (*lock.Holder, error) {
+	if w.Lock == nil {
+		return nil, fmt.Errorf("the workflow has no lock")
+	}
+	return lock.ForceUnlock(w.lockConfig())
+}
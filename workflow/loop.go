@@ -0,0 +1,331 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package workflow
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// DefaultLoopVar is the variable that holds the current item of a loop unless loop_var is set
+const DefaultLoopVar = "item"
+
+// loopKeys are the fields of a looped task that control the loop. They are removed from the task
+// that is run for each item.
+var loopKeys = []string{"loop", "loop_var", "serial", "between_batches", "max_fail_percentage"}
+
+// loopSpec contains the settings of a looped task
+type loopSpec struct {
+	loopVar        string
+	serial         int     // Batch size, 0 for a single batch
+	serialPercent  float64 // Batch size as a percentage of the items, 0 if serial is used
+	between        []map[string]any
+	maxFailPercent float64
+}
+
+// isLoop returns true if a task is to be run once for each item of a loop
+func isLoop(rawTask map[string]any) bool {
+	_, ok := rawTask["loop"]
+	return ok
+}
+
+// parseLoop parses the loop settings of a task, other than the items themselves
+func parseLoop(rawTask map[string]any) (loopSpec, error) {
+	spec := loopSpec{loopVar: DefaultLoopVar}
+
+	if v, ok := rawTask["loop_var"].(string); ok && v != "" {
+		spec.loopVar = v
+	}
+
+	switch v := rawTask["serial"].(type) {
+	case nil:
+	case int:
+		spec.serial = v
+	case float64:
+		spec.serial = int(v)
+	case string:
+		var err error
+		if strings.HasSuffix(v, "%") {
+			spec.serialPercent, err = strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+			if err == nil && (spec.serialPercent <= 0 || spec.serialPercent > 100) {
+				err = fmt.Errorf("percentage must be greater than 0 and at most 100")
+			}
+		} else {
+			spec.serial, err = strconv.Atoi(v)
+		}
+		if err != nil {
+			return spec, fmt.Errorf("invalid serial %q: %w", v, err)
+		}
+	default:
+		return spec, fmt.Errorf("serial must be a number or a percentage")
+	}
+	if spec.serial < 0 {
+		return spec, fmt.Errorf("serial must not be negative")
+	}
+
+	switch v := rawTask["max_fail_percentage"].(type) {
+	case nil:
+	case int:
+		spec.maxFailPercent = float64(v)
+	case float64:
+		spec.maxFailPercent = v
+	default:
+		return spec, fmt.Errorf("max_fail_percentage must be a number")
+	}
+
+	between, err := taskListValue(rawTask["between_batches"])
+	if err != nil {
+		return spec, fmt.Errorf("between_batches: %w", err)
+	}
+	spec.between = between
+	return spec, nil
+}
+
+// taskListValue converts a raw value into a list of tasks
+func taskListValue(raw any) ([]map[string]any, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	list, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("must be a list of tasks")
+	}
+	tasks := make([]map[string]any, 0, len(list))
+	for _, item := range list {
+		task, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("must be a list of tasks")
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// loopItems returns the items of a loop. The loop may be a list, or a string referring to a variable
+// holding a list, e.g. "{{instance_ids}}".
+func loopItems(raw any) ([]any, error) {
+	if s, ok := raw.(string); ok {
+		name := strings.TrimSpace(s)
+		if !strings.HasPrefix(name, "{{") || !strings.HasSuffix(name, "}}") {
+			return nil, fmt.Errorf("loop must be a list or a variable reference such as {{name}}")
+		}
+		value, exists := shared.LookupVar(strings.TrimSpace(name[2 : len(name)-2]))
+		if !exists {
+			return nil, fmt.Errorf("loop variable %s is not set", s)
+		}
+		raw = value
+	}
+
+	if raw == nil {
+		return nil, nil
+	}
+	if list, ok := raw.([]any); ok {
+		return shared.ResolveVars(list).([]any), nil
+	}
+
+	// Task data may hold typed slices such as []string
+	rv := reflect.ValueOf(raw)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("loop must be a list, got %T", raw)
+	}
+	items := make([]any, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, nil
+}
+
+// batchSize returns the number of items in each batch
+func (spec loopSpec) batchSize(items int) int {
+	switch {
+	case spec.serialPercent > 0:
+		return max(1, int(math.Ceil(float64(items)*spec.serialPercent/100)))
+	case spec.serial > 0:
+		return spec.serial
+	default:
+		return max(1, items)
+	}
+}
+
+// validateLoops checks the loop settings of all looped tasks
+func (w *Workflow) validateLoops() error {
+	for _, list := range w.lists() {
+		for i, rawTask := range list.tasks {
+			if !isLoop(rawTask) {
+				continue
+			}
+			if isAsync(rawTask) {
+				return fmt.Errorf("%s %d: a looped task cannot be run in the background", list.name, i+1)
+			}
+			if _, err := parseLoop(rawTask); err != nil {
+				return fmt.Errorf("%s %d: %w", list.name, i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// betweenLists returns the between_batches task lists of the looped tasks in the provided lists
+func betweenLists(lists []taskList) []taskList {
+	var between []taskList
+	for _, list := range lists {
+		for i, rawTask := range list.tasks {
+			if tasks, err := taskListValue(rawTask["between_batches"]); err == nil && len(tasks) > 0 {
+				between = append(between, taskList{name: fmt.Sprintf("%s %d between_batches", list.name, i+1), tasks: tasks})
+			}
+		}
+	}
+	return between
+}
+
+// runLoop runs a task once for each item of its loop, in batches if serial is set. After each batch
+// except the last, the between_batches tasks are run with the batch's items in batch_items and must
+// succeed. The loop is aborted if the percentage of failed items exceeds max_fail_percentage (0 by
+// default, so any failure aborts the loop at the end of the batch). It returns true if the workflow
+// should continue.
+func (w *Workflow) runLoop(pos position, sequence int, rawTask map[string]any) bool {
+	start := time.Now()
+
+	name, _ := rawTask["name"].(string)
+	taskType, _ := rawTask["task"].(string)
+	errorMessage, _ := rawTask["error_message"].(string)
+	taskContext := shared.TaskContext{
+		Name:         name,
+		Task:         taskType,
+		Sequence:     sequence,
+		ErrorMessage: errorMessage,
+	}
+
+	w.taskStart(shared.TaskInfo{
+		MessageType:  "task_start",
+		Phase:        pos.phase,
+		Parent:       pos.parent,
+		Sequence:     sequence,
+		Name:         name,
+		Task:         taskType,
+		Environment:  w.environment,
+		Instructions: rawTask,
+		Debug:        w.Debug,
+	})
+
+	result := w.loop(pos, rawTask, taskContext)
+	result.MessageType = "task_stop"
+
+	// changed_when and failed_when were applied to the result of each item, whose data they refer to, so only
+	// ignore_errors and notify apply to the result of the loop as a whole
+	loopTask := maps.Clone(rawTask)
+	delete(loopTask, "changed_when")
+	delete(loopTask, "failed_when")
+	return w.taskEnd(loopTask, placeResult(finishTask(result), pos, start))
+}
+
+// loop runs the items of a looped task and returns the result of the loop as a whole
+func (w *Workflow) loop(pos position, rawTask map[string]any, taskContext shared.TaskContext) shared.TaskResult {
+	spec, err := parseLoop(rawTask)
+	if err != nil {
+		return taskContext.Error("invalid loop", err)
+	}
+	items, err := loopItems(rawTask["loop"])
+	if err != nil {
+		return taskContext.Error("invalid loop", err)
+	}
+
+	// The task that is run for each item
+	itemTask := make(map[string]any, len(rawTask))
+	for k, v := range rawTask {
+		itemTask[k] = v
+	}
+	for _, k := range loopKeys {
+		delete(itemTask, k)
+	}
+
+	step := shared.NestedStep(pos.parent, taskContext.Sequence)
+	itemPos := position{phase: pos.phase, parent: step}
+	size := spec.batchSize(len(items))
+	batches := (len(items) + size - 1) / size
+
+	loopResults := make([]any, 0, len(items))
+	changed := false
+	processed, failed := 0, 0
+
+	for b := 0; b < batches; b++ {
+		first := b * size
+		last := min(first+size, len(items))
+
+		w.progress(shared.WorkflowEvent{
+			MessageType: "batch_start",
+			Msg:         fmt.Sprintf("task %s: batch %d/%d (items %d-%d of %d)", step, b+1, batches, first+1, last, len(items)),
+			Data:        map[string]any{"step": step, "batch": b + 1, "batches": batches, "first_item": first + 1, "last_item": last},
+		})
+
+		batchFailed := 0
+		for i := first; i < last; i++ {
+			restore := scopeVars(map[string]any{spec.loopVar: items[i], "loop_index": i + 1})
+			result := evaluateResult(itemTask, w.runTask(itemPos, i+1, itemTask))
+			restore()
+
+			// A failed item is reported as ignored, as max_fail_percentage decides whether the loop fails.
+			// If it does, the failure is reported by the result of the loop.
+			itemFailed := !result.Success && !result.Ignored
+			result.Ignored = result.Ignored || itemFailed
+			w.notify(itemTask, result)
+			stopped := !w.taskStop(result)
+
+			r := w.results[len(w.results)-1]
+			loopResults = append(loopResults, r.Data)
+			changed = changed || r.Changed
+			if stopped {
+				// The callback stopped the workflow
+				r = taskContext.Error(fmt.Sprintf("loop stopped after item %d of %d", i+1, len(items)), nil)
+				r.Data = map[string]any{"loop_results": loopResults}
+				r.Changed = changed
+				return r
+			}
+			processed++
+			if itemFailed {
+				batchFailed++
+			}
+		}
+		failed += batchFailed
+
+		w.progress(shared.WorkflowEvent{
+			MessageType: "batch_end",
+			Msg:         fmt.Sprintf("task %s: batch %d/%d complete, %d failed", step, b+1, batches, batchFailed),
+			Data:        map[string]any{"step": step, "batch": b + 1, "batches": batches, "failed": batchFailed},
+		})
+
+		if failed > 0 && float64(failed)*100/float64(processed) > spec.maxFailPercent {
+			r := taskContext.Error(fmt.Sprintf("loop aborted after batch %d/%d: %d of %d item(s) failed, exceeding max_fail_percentage %g",
+				b+1, batches, failed, processed, spec.maxFailPercent), nil)
+			r.Data = map[string]any{"loop_results": loopResults}
+			r.Changed = changed
+			return r
+		}
+
+		// Run the between_batches tasks before the next batch
+		if b < batches-1 && len(spec.between) > 0 {
+			restore := scopeVars(map[string]any{"batch_items": items[first:last]})
+			ok := w.runList(position{phase: pos.phase, parent: fmt.Sprintf("%s.between%d", step, b+1)}, spec.between, false)
+			restore()
+			if !ok {
+				r := taskContext.Error(fmt.Sprintf("loop aborted: between_batches tasks failed after batch %d/%d", b+1, batches), nil)
+				r.Data = map[string]any{"loop_results": loopResults}
+				r.Changed = changed
+				return r
+			}
+		}
+	}
+
+	r := taskContext.Result(true, fmt.Sprintf("%d item(s) in %d batch(es), %d failed", len(items), batches, failed),
+		map[string]any{"loop_results": loopResults})
+	r.Changed = changed
+	return r
+}
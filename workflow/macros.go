@@ -0,0 +1,172 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package workflow
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// MacroPrefix is the prefix of the task type used to invoke a macro, e.g. "macro.bake_ami"
+const MacroPrefix = "macro."
+
+// Macro is a named, reusable sequence of tasks that can be invoked like a task
+type Macro struct {
+	Description string           `yaml:"description"`
+	Params      []MacroParam     `yaml:"params"`
+	Tasks       []map[string]any `yaml:"tasks"`
+}
+
+// MacroParam declares a parameter of a macro. Parameters are available to the macro's tasks as variables.
+type MacroParam struct {
+	Name     string `yaml:"name"`
+	Required bool   `yaml:"required"`
+	Default  any    `yaml:"default"`
+}
+
+// macroName returns the name of the macro invoked by a task type, or an empty string if the task type
+// does not invoke a macro
+func macroName(taskType string) string {
+	return strings.TrimPrefix(taskType, MacroPrefix)
+}
+
+// validateMacros checks that every macro invocation refers to a macro and provides its required parameters
+func (w *Workflow) validateMacros() error {
+	for name, macro := range w.Macros {
+		for i, p := range macro.Params {
+			if p.Name == "" {
+				return fmt.Errorf("macro %s: parameter %d has no name", name, i+1)
+			}
+		}
+	}
+
+	for _, list := range w.lists() {
+		for i, rawTask := range list.tasks {
+			taskType, _ := rawTask["task"].(string)
+			if !strings.HasPrefix(taskType, MacroPrefix) {
+				continue
+			}
+			macro, ok := w.Macros[macroName(taskType)]
+			if !ok {
+				return fmt.Errorf("%s %d: unknown macro: %s", list.name, i+1, macroName(taskType))
+			}
+			for _, p := range macro.Params {
+				if _, ok := rawTask[p.Name]; p.Required && !ok {
+					return fmt.Errorf("%s %d: macro %s requires parameter %s", list.name, i+1, macroName(taskType), p.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// macroLists returns the task lists of all macros, sorted by macro name
+func (w *Workflow) macroLists() []taskList {
+	lists := make([]taskList, 0, len(w.Macros))
+	for _, name := range slices.Sorted(maps.Keys(w.Macros)) {
+		lists = append(lists, taskList{name: "macro " + name, tasks: w.Macros[name].Tasks})
+	}
+	return lists
+}
+
+// macroTask implements macro invocation. The macro's parameters are set as variables for the duration of
+// the macro, its tasks are run as nested tasks of the invoking task, and the data returned by its tasks
+// is returned as the invocation's data.
+func (w *Workflow) macroTask(pos position, rawTask map[string]any, taskContext shared.TaskContext) shared.TaskResult {
+	name := macroName(taskContext.Task)
+	macro, ok := w.Macros[name]
+	if !ok {
+		return taskContext.Error(fmt.Sprintf("unknown macro: %s", name), nil)
+	}
+
+	// A macro may invoke other macros, but not itself
+	if slices.Contains(w.macroStack, name) {
+		return taskContext.Error(fmt.Sprintf("macro %s invokes itself", name), nil)
+	}
+
+	// Resolve the parameters
+	params := make(map[string]any)
+	for _, p := range macro.Params {
+		value, ok := rawTask[p.Name]
+		if !ok {
+			if p.Required {
+				return taskContext.Error(fmt.Sprintf("macro %s requires parameter %s", name, p.Name), nil)
+			}
+			value = p.Default
+		}
+		params[p.Name] = shared.ResolveVars(value)
+	}
+
+	// Set the parameters as variables, restoring any previous values when the macro ends
+	restore := scopeVars(params)
+	defer restore()
+
+	w.macroStack = append(w.macroStack, name)
+	defer func() { w.macroStack = w.macroStack[:len(w.macroStack)-1] }()
+
+	// Run the macro's tasks nested under this task
+	first := len(w.results)
+	ok = w.runList(position{phase: pos.phase, parent: shared.NestedStep(pos.parent, taskContext.Sequence)}, macro.Tasks, false)
+
+	// Collect the data returned by the macro's tasks
+	data := make(map[string]any)
+	changed := false
+	var failed *shared.TaskResult
+	for i := first; i < len(w.results); i++ {
+		r := w.results[i]
+		if r.Success {
+			maps.Copy(data, r.Data)
+		} else if !r.Ignored {
+			failed = &w.results[i]
+		}
+		changed = changed || r.Changed
+	}
+
+	var result shared.TaskResult
+	if !ok {
+		msg := fmt.Sprintf("macro %s failed", name)
+		if failed != nil {
+			msg = fmt.Sprintf("macro %s failed at task %s: %s", name, failed.Step(), failed.Msg)
+		}
+		result = taskContext.Error(msg, nil)
+		result.Data = data
+	} else {
+		result = taskContext.Result(true, fmt.Sprintf("macro %s completed %d task(s)", name, len(macro.Tasks)), data)
+	}
+	result.Changed = changed
+
+	// The macro's tasks have already set their variables
+	result.NoVars = true
+	return result
+}
+
+// scopeVars sets variables and returns a function that restores their previous values. A variable
+// that was changed by a task in the meantime is left alone, so that the task's result is not lost.
+func scopeVars(vars map[string]any) func() {
+	previous := make(map[string]any)
+	for name, value := range vars {
+		if old, ok := shared.LookupVar(name); ok {
+			previous[name] = old
+		}
+		shared.SetVar(name, value)
+	}
+
+	return func() {
+		for name, value := range vars {
+			if !reflect.DeepEqual(shared.GetVar(name), value) {
+				continue
+			}
+			if old, ok := previous[name]; ok {
+				shared.SetVar(name, old)
+			} else {
+				shared.UnsetVar(name)
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package approval
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// DefaultPhrase is the confirmation phrase used if none is configured
+const DefaultPhrase = "approve"
+
+// DefaultTimeout is the time to wait for approval, in seconds, if no timeout is configured
+const DefaultTimeout = 600
+
+type Task struct {
+	Context shared.TaskContext `yaml:"context" json:"context"`
+	Message string             `yaml:"message" json:"message"` // Message shown to the operator
+	Phrase  string             `yaml:"phrase" json:"phrase"`   // Phrase the operator must type to approve
+	Timeout int                `yaml:"timeout" json:"timeout"` // Time to wait in seconds
+}
+
+func init() {
+	shared.RegisterTask("approval", func(context shared.TaskContext) shared.Task {
+		return &Task{Context: context}
+	})
+}
+
+func (t *Task) Execute() shared.TaskResult {
+	var err error
+
+	if err = json.Unmarshal(t.Context.Instructions, t); err != nil {
+		return t.Context.Error("failed to deserialize data", err)
+	}
+
+	shared.ProcessVars(t)
+
+	if t.Phrase == "" {
+		t.Phrase = DefaultPhrase
+	}
+	if t.Timeout < 0 {
+		return t.Context.Error("timeout must not be negative", nil)
+	}
+	if t.Timeout == 0 {
+		t.Timeout = DefaultTimeout
+	}
+
+	if t.Context.Debug {
+		shared.DumpTask(t)
+	}
+
+	if t.Context.DryRun {
+		return t.Context.Result(true, "Dry run...would wait for approval", nil)
+	}
+
+	// Approval requires an operator, so an unattended run fails rather than waiting
+	if !shared.IsTerminal() {
+		return t.Context.Error("approval requires an interactive terminal", nil)
+	}
+
+	if t.Message != "" {
+		shared.Printf("%s\n", t.Message)
+	}
+	prompt := fmt.Sprintf("Type '%s' within %d seconds to approve: ", t.Phrase, t.Timeout)
+	answer, err := shared.Prompt(prompt, time.Duration(t.Timeout)*time.Second, t.Context.Cancel)
+	shared.Println()
+	switch {
+	case errors.Is(err, shared.ErrPromptTimeout):
+		return t.Context.Error(fmt.Sprintf("approval not given within %d seconds", t.Timeout), nil)
+	case err != nil:
+		return t.Context.Error("approval not given", err)
+	case answer != t.Phrase:
+		return t.Context.Error("approval declined", nil)
+	}
+
+	user := shared.CurrentUser()
+	return t.Context.Result(true, fmt.Sprintf("Approved by %s", user), map[string]any{
+		"approved":    true,
+		"approved_by": user,
+		"approved_at": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// Plan reports the approval without requesting it. Nothing is changed in plan mode, so there is
+// nothing to approve.
+func (t *Task) Plan() shared.TaskResult {
+	if err := json.Unmarshal(t.Context.Instructions, t); err != nil {
+		return t.Context.Error("failed to deserialize data", err)
+	}
+
+	shared.ProcessVars(t)
+
+	return t.Context.Result(true, "Plan mode, would wait for approval", nil)
+}
@@ -25,3 +25,8 @@ func (t *Task) Execute() shared.TaskResult {
 	}
 	return t.Context.Error("Dryrun is required but not enabled, returning error", errors.New("dryrun required but not enabled"))
 }
+
+// Plan succeeds, since plan mode makes no changes
+func (t *Task) Plan() shared.TaskResult {
+	return t.Context.Result(true, "Plan mode is confirmed", nil)
+}
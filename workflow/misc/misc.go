@@ -4,6 +4,7 @@
 package misc
 
 import (
+	_ "github.com/OpsBlade/OpsBlade/workflow/misc/approval"
 	_ "github.com/OpsBlade/OpsBlade/workflow/misc/dryrun"
 	_ "github.com/OpsBlade/OpsBlade/workflow/misc/exitIf"
 	_ "github.com/OpsBlade/OpsBlade/workflow/misc/sleep"
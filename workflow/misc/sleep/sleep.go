@@ -36,13 +36,30 @@ func (t *Task) Execute() shared.TaskResult {
 	}
 
 	if t.Context.Debug {
-		fmt.Printf("Sleeping for %d seconds...", t.Sleep)
+		shared.Printf("Sleeping for %d seconds...", t.Sleep)
 	}
 
 	if t.Context.DryRun {
-		fmt.Printf("Dry run...would sleep %d seconds", t.Sleep)
+		shared.Printf("Dry run...would sleep %d seconds", t.Sleep)
 	} else {
-		time.Sleep(time.Duration(t.Sleep) * time.Second)
+		if !t.Context.Sleep(time.Duration(t.Sleep) * time.Second) {
+			return t.Context.Error("sleep cancelled", nil)
+		}
 	}
 	return t.Context.Result(true, fmt.Sprintf("Slept for %d seconds", t.Sleep), nil)
 }
+
+// Plan reports the sleep without sleeping
+func (t *Task) Plan() shared.TaskResult {
+	if err := json.Unmarshal(t.Context.Instructions, t); err != nil {
+		return t.Context.Error("failed to deserialize data", err)
+	}
+
+	shared.ProcessVars(t)
+
+	if t.Sleep < 1 {
+		return t.Context.Error("Sleep time must be one second or greater", nil)
+	}
+
+	return t.Context.Result(true, fmt.Sprintf("Plan mode, would sleep %d seconds", t.Sleep), nil)
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package workflow
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/OpsBlade/OpsBlade/policy"
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// policyWorkflow returns the workflow information that policy rules refer to as workflow.*
+func (w *Workflow) policyWorkflow() map[string]any {
+	tags := make([]any, 0, len(w.Tags))
+	for _, tag := range w.Tags {
+		tags = append(tags, tag)
+	}
+	return map[string]any{
+		"name":      w.Name,
+		"file":      w.source,
+		"tags":      tags,
+		"env":       w.Env,
+		"dryrun":    w.DryRun,
+		"plan":      w.planMode,
+		"read_only": w.isReadOnly(),
+	}
+}
+
+// inheritPolicy passes the policy and tags of the workflow to a child workflow, so that a child cannot
+// escape the rules that apply to its parent
+func (w *Workflow) inheritPolicy(child *Workflow) {
+	child.policy = w.policy
+	for _, tag := range w.Tags {
+		if !slices.Contains(child.Tags, tag) {
+			child.Tags = append(child.Tags, tag)
+		}
+	}
+}
+
+// checkPolicy evaluates the policy against a registered task before it is started. The task's target is
+// described by a separate instance of the task, so that the instance that is executed starts from its
+// instructions. A violation fails the task and is reported as a policy_violation event. It returns false
+// and the result of the task if the task may not be executed.
+func (w *Workflow) checkPolicy(pos position, rawTask map[string]any, constructor func(shared.TaskContext) shared.Task,
+	taskContext shared.TaskContext) (shared.TaskResult, bool) {
+	if w.policy == nil {
+		return shared.TaskResult{}, true
+	}
+
+	instructions, _ := shared.ResolveVars(rawTask).(map[string]any)
+	in := policy.Input{Task: instructions, Workflow: w.policyWorkflow()}
+	if describer, ok := constructor(taskContext).(shared.Describer); ok {
+		in.Describe = describer.Describe
+	}
+
+	violation, err := w.policy.Evaluate(taskContext.Task, in)
+	if err != nil {
+		return taskContext.Error("policy evaluation failed", err), false
+	}
+	if violation == nil {
+		return shared.TaskResult{}, true
+	}
+
+	label := shared.TaskResult{Phase: pos.phase, Parent: pos.parent, Sequence: taskContext.Sequence}
+	w.progress(shared.WorkflowEvent{
+		MessageType: "policy_violation",
+		Msg:         fmt.Sprintf("task %s [%s] %s", label.Step(), taskContext.Task, violation.Error()),
+		Data: map[string]any{
+			"step":        label.Step(),
+			"name":        taskContext.Name,
+			"task":        taskContext.Task,
+			"rule":        violation.Rule,
+			"description": violation.Description,
+		},
+	})
+	return taskContext.Error(fmt.Sprintf("Task refused: %s", violation.Error()), nil), false
+}
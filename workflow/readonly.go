@@ -0,0 +1,68 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package workflow
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// isReadOnly returns true if the workflow may not run tasks that change something, either because
+// read_only is set in the workflow file or its environment, or because read-only mode was requested by the caller
+func (w *Workflow) isReadOnly() bool {
+	return w.ReadOnly || w.readOnly || (w.selected != nil && w.selected.ReadOnly)
+}
+
+// validateReadOnly refuses a read-only workflow that contains a task registered as mutating, or runs a child
+// workflow that does, so that the workflow fails before any task is run rather than part way through
+func (w *Workflow) validateReadOnly() error {
+	if !w.isReadOnly() {
+		return nil
+	}
+	visited := make(map[string]bool)
+	if abs, err := filepath.Abs(w.source); err == nil && w.source != "" {
+		visited[abs] = true
+	}
+	return w.checkReadOnly(visited)
+}
+
+// checkReadOnly refuses mutating tasks in the task lists of the workflow and of the child workflows run by its
+// workflow_run tasks. A child workflow whose file is set by a variable is checked when it runs.
+func (w *Workflow) checkReadOnly(visited map[string]bool) error {
+	for _, list := range w.lists() {
+		for i, rawTask := range list.tasks {
+			taskType, _ := rawTask["task"].(string)
+			if shared.IsMutating(taskType) {
+				return fmt.Errorf("%s %d: %s changes something and cannot be run in read-only mode", list.name, i+1, taskType)
+			}
+			file, _ := rawTask["file"].(string)
+			if taskType != "workflow_run" || file == "" || strings.Contains(file, "{{") {
+				continue
+			}
+
+			// Relative paths are relative to the directory of the parent workflow file
+			if !filepath.IsAbs(file) && w.source != "" {
+				file = filepath.Join(filepath.Dir(w.source), file)
+			}
+			abs, err := filepath.Abs(file)
+			if err != nil || visited[abs] {
+				continue
+			}
+			visited[abs] = true
+
+			child := New()
+			w.inheritSignature(child)
+			if err = child.Load(file); err != nil {
+				return fmt.Errorf("%s %d: unable to load workflow %s: %w", list.name, i+1, file, err)
+			}
+			if err = child.checkReadOnly(visited); err != nil {
+				return fmt.Errorf("%s %d: workflow %s: %w", list.name, i+1, file, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package workflow
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+
+	"github.com/OpsBlade/OpsBlade/signature"
+)
+
+// verifySignature refuses a workflow file that is not signed by a trusted key, if trusted keys are set.
+// A child workflow file that is covered by the signature of a parent must be the version that was signed.
+// Other child workflow files, such as ones named with variables, must be signed themselves.
+func (w *Workflow) verifySignature(filename string, data []byte) error {
+	if len(w.trustedKeys) == 0 {
+		return nil
+	}
+	if filename == "" {
+		return fmt.Errorf("a signature is required, and a workflow read from stdin cannot be verified")
+	}
+
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+	if hash, ok := w.signedFiles[abs]; ok {
+		if signature.Hash(data) != hash {
+			return fmt.Errorf("%s was modified after it was signed", filename)
+		}
+		return nil
+	}
+
+	verified, err := signature.Verify(filename, data, w.trustedKeys)
+	if err != nil {
+		return err
+	}
+	if w.signedFiles == nil {
+		w.signedFiles = make(map[string]string)
+	}
+	maps.Copy(w.signedFiles, verified.Files)
+	return nil
+}
+
+// inheritSignature passes the trusted keys and the files covered by verified signatures to a child
+// workflow, before it is loaded
+func (w *Workflow) inheritSignature(child *Workflow) {
+	child.trustedKeys = w.trustedKeys
+	child.signedFiles = maps.Clone(w.signedFiles)
+}
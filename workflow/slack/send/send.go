@@ -5,6 +5,7 @@ package send
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/OpsBlade/OpsBlade/services/cloudslack"
 	"github.com/OpsBlade/OpsBlade/shared"
@@ -63,8 +64,11 @@ func (t *Task) Execute() shared.TaskResult {
 		return t.Context.Error("failed to create Slack client", err)
 	}
 
+	data["slack_subject"] = t.Subject
+	data["slack_body"] = t.Body
+
 	if t.Context.DryRun {
-		return t.Context.Result(true, "DryRun, no message sent", data)
+		return t.Context.DryRunResult(fmt.Sprintf("send Slack message: %s", t.Subject), data)
 	}
 
 	err = s.SendMessage(t.Subject, msg)
@@ -72,7 +76,23 @@ func (t *Task) Execute() shared.TaskResult {
 		return t.Context.Error("failed to send Slack message", err)
 	}
 
-	data["slack_subject"] = t.Subject
-	data["slack_body"] = t.Body
 	return t.Context.Result(true, "Slack message sent", data)
 }
+
+// Plan describes the message Execute would send, without sending it
+func (t *Task) Plan() shared.TaskResult {
+	if err := json.Unmarshal(t.Context.Instructions, t); err != nil {
+		return t.Context.Error("failed to deserialize data", err)
+	}
+
+	shared.ProcessVars(t)
+
+	return t.Context.Planned([]shared.PlannedChange{
+		{
+			Action:      "SendMessage",
+			Target:      "SLACK_WEBHOOK" + t.EnvSuffix,
+			Description: fmt.Sprintf("send Slack message: %s", t.Subject),
+			Details:     map[string]any{"body": t.Body},
+		},
+	}, map[string]string{"slack_subject": t.Subject, "slack_body": t.Body})
+}
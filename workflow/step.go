@@ -0,0 +1,43 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package workflow
+
+import (
+	"strings"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// Operator decisions in step mode
+const (
+	stepContinue = "continue"
+	stepSkip     = "skip"
+	stepAbort    = "abort"
+)
+
+// stepPrompt shows the instructions of a task, with variables resolved, and asks the operator whether
+// to run it, skip it, or abort the workflow. If no answer can be read, the workflow is aborted.
+func (w *Workflow) stepPrompt(pos position, sequence int, rawTask map[string]any) string {
+	label := shared.TaskResult{Phase: pos.phase, Parent: pos.parent, Sequence: sequence}
+	shared.Printf("* Next task %s:\n", label.Step())
+	shared.Printf("%s", shared.AnyToYAMLIndent(shared.ResolveVars(rawTask), "  ", 2))
+
+	for {
+		answer, err := shared.Prompt("Continue, skip, or abort? [c/s/a]: ", 0, nil)
+		if err != nil {
+			return stepAbort
+		}
+		switch strings.ToLower(answer) {
+		case "c", stepContinue:
+			shared.Println()
+			return stepContinue
+		case "s", stepSkip:
+			shared.Println()
+			return stepSkip
+		case "a", stepAbort:
+			shared.Println()
+			return stepAbort
+		}
+	}
+}
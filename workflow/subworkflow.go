@@ -0,0 +1,199 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+)
+
+// TaskWorkflowRun is an engine built-in task that runs another workflow file as a child workflow
+const TaskWorkflowRun = "workflow_run"
+
+// workflowRun contains the settings of a workflow_run task
+type workflowRun struct {
+	File    string         `json:"file"`    // Workflow file, relative to the directory of the parent workflow
+	Inputs  map[string]any `json:"inputs"`  // Variables passed to the child workflow
+	Outputs []string       `json:"outputs"` // Variables of the child workflow returned as data
+}
+
+// childCallback forwards task information, results, and events from a child workflow to its parent,
+// placing the child's tasks under the position of the workflow_run task
+type childCallback struct {
+	parent *Workflow
+	phase  string // Phase of the workflow_run task
+	step   string // Position of the workflow_run task within its phase
+	err    string // Last error reported by the child workflow
+}
+
+// nest returns the phase and parent of a child task as seen by the parent workflow
+func (c *childCallback) nest(phase, parent string) (string, string) {
+	p := c.step
+	if phase != "" {
+		p += "." + phase
+	}
+	if parent != "" {
+		p += "." + parent
+	}
+	return c.phase, p
+}
+
+// OnStart forwards the start of a child task to the parent
+func (c *childCallback) OnStart(info shared.TaskInfo) bool {
+	info.Phase, info.Parent = c.nest(info.Phase, info.Parent)
+	return c.parent.taskStart(info)
+}
+
+// OnStop forwards the result of a child task to the parent, whose callback may stop the child workflow
+func (c *childCallback) OnStop(result shared.TaskResult) bool {
+	result.Phase, result.Parent = c.nest(result.Phase, result.Parent)
+	return c.parent.taskStop(result)
+}
+
+// OnEvent forwards a child workflow event to the parent with the nesting path
+func (c *childCallback) OnEvent(event shared.WorkflowEvent) {
+	if event.MessageType == "workflow_error" {
+		c.err = event.Msg
+	}
+
+	path := c.step
+	if c.phase != "" {
+		path = c.phase + "." + path
+	}
+	if event.Path != "" {
+		path += "." + event.Path
+	}
+	event.Path = path
+
+	// Progress events are shown in every output mode, other workflow events depend on the mode
+	switch event.MessageType {
+	case "batch_start", "batch_end", "lock_wait", "lock_lost", "window_wait", "async_wait":
+		c.parent.progress(event)
+		return
+	}
+	c.parent.event(event)
+}
+
+// workflowRunTask implements the workflow_run built-in task. The child workflow runs with its own
+// variables, initialized from inputs. Only the declared outputs are returned to the parent.
+func (w *Workflow) workflowRunTask(pos position, _ map[string]any, taskContext shared.TaskContext) shared.TaskResult {
+	var spec workflowRun
+	if err := json.Unmarshal(taskContext.Instructions, &spec); err != nil {
+		return taskContext.Error("failed to deserialize workflow_run task", err)
+	}
+
+	file, _ := shared.ResolveVars(spec.File).(string)
+	if file == "" {
+		return taskContext.Error("file is required", nil)
+	}
+
+	// Relative paths are relative to the directory of the parent workflow file
+	if !filepath.IsAbs(file) && w.source != "" {
+		file = filepath.Join(filepath.Dir(w.source), file)
+	}
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return taskContext.Error("unable to resolve workflow file", err)
+	}
+
+	// Prevent a workflow from running itself, directly or indirectly
+	ancestors := slices.Clone(w.ancestors)
+	if len(ancestors) == 0 && w.source != "" {
+		if absSource, err := filepath.Abs(w.source); err == nil {
+			ancestors = append(ancestors, absSource)
+		}
+	}
+	if slices.Contains(ancestors, absFile) {
+		return taskContext.Error(fmt.Sprintf("workflow %s runs itself", file), nil)
+	}
+
+	// The child's variables replace the parent's while it runs, so the parent's background tasks would
+	// read and set the child's variables
+	if len(w.pending) > 0 {
+		ids := make([]string, 0, len(w.pending))
+		for _, job := range w.pending {
+			ids = append(ids, job.id)
+		}
+		return taskContext.Error(fmt.Sprintf("workflow %s cannot be run while background tasks are running, await them first: %s",
+			file, strings.Join(ids, ", ")), nil)
+	}
+
+	// Inputs are resolved in the parent's variable scope
+	inputs, _ := shared.ResolveVars(spec.Inputs).(map[string]any)
+
+	cb := &childCallback{parent: w, phase: pos.phase, step: shared.NestedStep(pos.parent, taskContext.Sequence)}
+	child := New(WithCallback(cb))
+	w.inheritSignature(child)
+	if err = child.Load(file); err != nil {
+		return taskContext.Error(fmt.Sprintf("unable to load workflow %s", file), err)
+	}
+
+	// A dry run of the parent is always a dry run of the child
+	child.DryRun = child.DryRun || taskContext.DryRun
+	child.Debug = child.Debug || taskContext.Debug
+	if child.Env == "" {
+		child.Env = taskContext.Env
+	}
+	child.ancestors = append(ancestors, absFile)
+	child.stepMode = w.stepMode
+	child.planMode = w.planMode
+	child.readOnly = w.isReadOnly() // A read-only parent is always a read-only child
+	w.inheritPolicy(child)
+
+	// A child workflow cannot escape the AWS guard of its parent
+	if child.AWSGuard == nil {
+		child.AWSGuard = w.AWSGuard
+	} else if w.AWSGuard != nil {
+		child.AWSGuard.SetParent(w.AWSGuard)
+	}
+	child.confirmed = w.confirmed
+	child.audit = w.audit // A child workflow cannot escape the audit log of its parent
+	w.inheritLocks(child)
+	child.clock = w.clock
+	child.parentGates = w.gates // A child workflow cannot escape the windows of its parent
+	child.environment = w.environment
+	child.parentEnvironment = w.selected
+
+	// Run the child with its own variables
+	first := len(w.results)
+	parentVars := shared.SwapVars(inputs)
+	ok := child.Execute()
+	childVars := shared.SwapVars(parentVars)
+
+	changed := false
+	for _, r := range w.results[first:] {
+		changed = changed || r.Changed
+	}
+
+	// Capture the declared outputs
+	data := make(map[string]any)
+	var missing []string
+	for _, name := range spec.Outputs {
+		if value, exists := childVars[name]; exists {
+			data[name] = value
+		} else {
+			missing = append(missing, name)
+		}
+	}
+
+	var result shared.TaskResult
+	switch {
+	case !ok && cb.err != "":
+		result = taskContext.Error(fmt.Sprintf("workflow %s failed: %s", file, cb.err), nil)
+	case !ok:
+		result = taskContext.Error(fmt.Sprintf("workflow %s failed", file), nil)
+	case len(missing) > 0:
+		result = taskContext.Error(fmt.Sprintf("workflow %s did not set outputs: %v", file, missing), nil)
+	default:
+		result = taskContext.Result(true, fmt.Sprintf("workflow %s completed", file), data)
+	}
+	result.Data = data
+	result.Changed = changed
+	return result
+}
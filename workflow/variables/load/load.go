@@ -12,9 +12,12 @@ import (
 )
 
 type Task struct {
-	Context  shared.TaskContext `yaml:"context" json:"context"`
-	FileName string             `yaml:"filename" json:"filename"`
-	Fields   []string           `yaml:"fields" json:"fields"`
+	Context       shared.TaskContext `yaml:"context" json:"context"`
+	FileName      string             `yaml:"filename" json:"filename"`
+	Fields        []string           `yaml:"fields" json:"fields"`
+	Encrypt       bool               `yaml:"encrypt" json:"encrypt"`               // Require the file to be encrypted
+	PassphraseEnv string             `yaml:"passphrase_env" json:"passphrase_env"` // Environment variable holding the passphrase
+	KeyFile       string             `yaml:"key_file" json:"key_file"`             // File holding the passphrase
 }
 
 func init() {
@@ -32,17 +35,31 @@ func (t *Task) Execute() shared.TaskResult {
 
 	shared.ProcessVars(t)
 
-	file, err := os.Open(t.FileName)
+	data, err := os.ReadFile(t.FileName)
 	if err != nil {
 		return t.Context.Error("failed to open file", err)
 	}
-	defer func(file *os.File) {
-		_ = file.Close()
-	}(file)
+
+	// Encrypted files are recognized by their header
+	if shared.IsEncrypted(data) {
+		// The passphrase may be set in the task's env file
+		env, err := shared.LoadEnv(t.Context.Env)
+		if err != nil {
+			return t.Context.Error("failed to read env file", err)
+		}
+		passphrase, err := shared.Passphrase(t.PassphraseEnv, t.KeyFile, env)
+		if err != nil {
+			return t.Context.Error("failed to obtain passphrase", err)
+		}
+		if data, err = shared.Decrypt(data, passphrase); err != nil {
+			return t.Context.Error("failed to decrypt variables", err)
+		}
+	} else if t.Encrypt {
+		return t.Context.Error(fmt.Sprintf("%s is not encrypted", t.FileName), nil)
+	}
 
 	v := make(map[string]any)
-	decoder := json.NewDecoder(file)
-	if err = decoder.Decode(&v); err != nil {
+	if err = json.Unmarshal(data, &v); err != nil {
 		return t.Context.Error("failed to deserialize variables", err)
 	}
 
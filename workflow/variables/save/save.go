@@ -6,15 +6,17 @@ package save
 import (
 	"encoding/json"
 	"fmt"
-	"os"
 
 	"github.com/OpsBlade/OpsBlade/shared"
 )
 
 type Task struct {
-	FileName string             `yaml:"filename" json:"filename"` // Filename to save variables to
-	Context  shared.TaskContext `yaml:"context" json:"context"`   // Task context
-	Fields   []string           `yaml:"fields" json:"fields"`     // Fields to return
+	FileName      string             `yaml:"filename" json:"filename"`             // Filename to save variables to
+	Context       shared.TaskContext `yaml:"context" json:"context"`               // Task context
+	Fields        []string           `yaml:"fields" json:"fields"`                 // Fields to return
+	Encrypt       bool               `yaml:"encrypt" json:"encrypt"`               // Encrypt the file
+	PassphraseEnv string             `yaml:"passphrase_env" json:"passphrase_env"` // Environment variable holding the passphrase
+	KeyFile       string             `yaml:"key_file" json:"key_file"`             // File holding the passphrase
 }
 
 func init() {
@@ -40,28 +42,70 @@ func (t *Task) Execute() shared.TaskResult {
 	selectedVars := shared.SelectFields(vars, t.Fields)
 
 	if t.Context.Debug {
-		fmt.Println("Variables to be saved:")
-		fmt.Println(shared.Dump(selectedVars))
+		shared.Println("Variables to be saved:")
+		shared.Println(shared.Dump(selectedVars))
 	}
 
 	if t.Context.DryRun {
-		return t.Context.Result(true, fmt.Sprintf("Dry run: would save variables to %s", t.FileName), nil)
+		r := t.Context.DryRunResult(fmt.Sprintf("save variables to %s", t.FileName), selectedVars)
+		r.NoVars = true
+		return r
 	}
 
-	file, err := os.Create(t.FileName)
+	// Secret values are never written to variable files
+	data, err := json.Marshal(shared.RedactAny(selectedVars))
 	if err != nil {
-		return t.Context.Error("failed to create file", err)
+		return t.Context.Error("failed to serialize variables", err)
+	}
+	data = append(data, '\n')
+
+	msg := fmt.Sprintf("Saved variables to %s", t.FileName)
+	if t.Encrypt {
+		// The passphrase may be set in the task's env file
+		env, err := shared.LoadEnv(t.Context.Env)
+		if err != nil {
+			return t.Context.Error("failed to read env file", err)
+		}
+		passphrase, err := shared.Passphrase(t.PassphraseEnv, t.KeyFile, env)
+		if err != nil {
+			return t.Context.Error("failed to obtain passphrase", err)
+		}
+		if data, err = shared.Encrypt(data, passphrase); err != nil {
+			return t.Context.Error("failed to encrypt variables", err)
+		}
+		msg = fmt.Sprintf("Saved encrypted variables to %s", t.FileName)
 	}
-	defer func(file *os.File) {
-		_ = file.Close()
-	}(file)
 
-	encoder := json.NewEncoder(file)
-	if err = encoder.Encode(selectedVars); err != nil {
-		return t.Context.Error("failed to serialize variables", err)
+	// The file is only readable by the owner, and replaced atomically so that a failed save does not
+	// leave a partial file
+	if err = shared.WriteFileAtomic(t.FileName, data, 0600); err != nil {
+		return t.Context.Error("failed to write file", err)
+	}
+
+	r := t.Context.Result(true, msg, shared.SelectFields(vars, t.Fields))
+
+	// Do not save variables from this data
+	r.NoVars = true
+	return r
+}
+
+// Plan describes the file Execute would write, without writing it
+func (t *Task) Plan() shared.TaskResult {
+	if err := json.Unmarshal(t.Context.Instructions, t); err != nil {
+		return t.Context.Error("failed to deserialize data", err)
 	}
 
-	r := t.Context.Result(true, fmt.Sprintf("Saved variables to %s", t.FileName), shared.SelectFields(vars, t.Fields))
+	shared.ProcessVars(t)
+
+	selectedVars := shared.SelectFields(shared.GetVars(), t.Fields)
+	r := t.Context.Planned([]shared.PlannedChange{
+		{
+			Action:      "Write",
+			Target:      t.FileName,
+			Description: "save variables to file",
+			Details:     map[string]any{"encrypt": t.Encrypt},
+		},
+	}, selectedVars)
 
 	// Do not save variables from this data
 	r.NoVars = true
@@ -0,0 +1,109 @@
+// Copyright (c) 2025 Tenebris Technologies Inc.
+// This software is licensed under the MIT License (see LICENSE for details).
+
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/OpsBlade/OpsBlade/shared"
+	"github.com/OpsBlade/OpsBlade/window"
+)
+
+// baseDir returns the directory that files named in the workflow are relative to
+func (w *Workflow) baseDir() string {
+	if w.source == "" {
+		return ""
+	}
+	return filepath.Dir(w.source)
+}
+
+// validateWindows compiles the window of the workflow, including the gates inherited from a parent
+// workflow, and checks the windows of its tasks
+func (w *Workflow) validateWindows() error {
+	w.gates = slices.Clone(w.parentGates)
+	if w.Window != nil {
+		gate, err := w.Window.Compile(w.baseDir())
+		if err != nil {
+			return fmt.Errorf("window: %w", err)
+		}
+		w.gates = append(w.gates, gate)
+	}
+
+	for _, list := range w.lists() {
+		for i, rawTask := range list.tasks {
+			if _, err := w.taskGate(rawTask); err != nil {
+				return fmt.Errorf("%s %d: window: %w", list.name, i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// taskGate compiles the window setting of a task, if it has one
+func (w *Workflow) taskGate(rawTask map[string]any) (*window.Gate, error) {
+	raw, ok := rawTask["window"]
+	if !ok {
+		return nil, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var settings window.Window
+	if err = json.Unmarshal(data, &settings); err != nil {
+		return nil, err
+	}
+	return settings.Compile(w.baseDir())
+}
+
+// checkWindow checks that a task may run now. Tasks that change something must be in the windows of the
+// workflow and its parents, and a task with its own window must be in it. A closed window is waited for
+// if it opens within the window's wait, otherwise the task fails. Dry runs and plans do not change
+// anything, so they are not checked. It returns false and the result of the task if it may not run.
+func (w *Workflow) checkWindow(pos position, rawTask map[string]any, taskContext shared.TaskContext) (shared.TaskResult, bool) {
+	if taskContext.DryRun || w.planMode {
+		return shared.TaskResult{}, true
+	}
+
+	var gates []*window.Gate
+	if shared.IsMutating(taskContext.Task) {
+		gates = append(gates, w.gates...)
+	}
+	gate, err := w.taskGate(rawTask)
+	if err != nil {
+		return taskContext.Error("invalid window", err), false
+	}
+	if gate != nil {
+		gates = append(gates, gate)
+	}
+	if len(gates) == 0 {
+		return shared.TaskResult{}, true
+	}
+
+	label := shared.TaskResult{Phase: pos.phase, Parent: pos.parent, Sequence: taskContext.Sequence}
+	waiting := func(closed *window.Closed) {
+		w.progress(shared.WorkflowEvent{
+			MessageType: "window_wait",
+			Msg:         fmt.Sprintf("task %s [%s] waiting: %s", label.Step(), taskContext.Task, closed.Error()),
+			Data:        map[string]any{"step": label.Step(), "name": taskContext.Name, "task": taskContext.Task, "opens": closed.Next},
+		})
+	}
+	for _, g := range gates {
+		if err = g.Wait(w.clock, taskContext.Cancel, waiting); err != nil {
+			return taskContext.Error(fmt.Sprintf("Task refused: %s", err.Error()), nil), false
+		}
+	}
+
+	// Waiting for one window may have closed another
+	now := w.clock.Now()
+	for _, g := range gates {
+		if err = g.Check(now); err != nil {
+			return taskContext.Error(fmt.Sprintf("Task refused: %s", err.Error()), nil), false
+		}
+	}
+	return shared.TaskResult{}, true
+}
@@ -4,13 +4,24 @@
 package workflow
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"gopkg.in/yaml.v3"
 	"io"
 	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
 
+	"github.com/OpsBlade/OpsBlade/audit"
+	"github.com/OpsBlade/OpsBlade/lock"
+	"github.com/OpsBlade/OpsBlade/policy"
 	"github.com/OpsBlade/OpsBlade/shared"
+	"github.com/OpsBlade/OpsBlade/signature"
+	"github.com/OpsBlade/OpsBlade/window"
 
 	// Import all task packages so that they register.
 	// Each task's init function registers the task with the shared.TaskRegistry and includes
@@ -29,13 +40,80 @@ import (
 	_ "github.com/OpsBlade/OpsBlade/workflow/variables"
 )
 
+// Workflow phases. Tasks in the main task list have no phase.
+const (
+	PhaseOnFailure = "on_failure" // Tasks run after the main task list fails
+	PhaseOnSuccess = "on_success" // Tasks run after the main task list succeeds
+)
+
+// Output modes
+const (
+	OutputText   = "text"   // Human-readable output
+	OutputJSON   = "json"   // Pretty-printed JSON objects
+	OutputNDJSON = "ndjson" // One compact JSON object per line, human output sent to stderr
+)
+
 type Workflow struct {
-	Env      string           `yaml:"env"`
-	DryRun   bool             `yaml:"dryrun"`
-	Debug    bool             `yaml:"debug"`
-	JSON     bool             `yaml:"json"`
-	Tasks    []map[string]any `yaml:"tasks"`
-	callback shared.Callback  `yaml:"-"`
+	Env               string                  `yaml:"env"`
+	DryRun            bool                    `yaml:"dryrun"`
+	ReadOnly          bool                    `yaml:"read_only"`
+	Debug             bool                    `yaml:"debug"`
+	JSON              bool                    `yaml:"json"`
+	Output            string                  `yaml:"output"`
+	Name              string                  `yaml:"name"`
+	Tags              []string                `yaml:"tags"`
+	AWSGuard          *shared.AWSGuard        `yaml:"aws_guard"`
+	Secrets           shared.SecretsConfig    `yaml:"secrets"`
+	AuditLog          string                  `yaml:"audit_log"`
+	Lock              *lock.Config            `yaml:"lock"`
+	Window            *window.Window          `yaml:"window"`
+	Tasks             []map[string]any        `yaml:"tasks"`
+	OnFailure         []map[string]any        `yaml:"on_failure"`
+	OnSuccess         []map[string]any        `yaml:"on_success"`
+	Handlers          []map[string]any        `yaml:"handlers"`
+	Macros            map[string]Macro        `yaml:"macros"`
+	Environments      map[string]*Environment `yaml:"environments"`
+	callback          shared.Callback         `yaml:"-"`
+	source            string                  `yaml:"-"`
+	runID             string                  `yaml:"-"`
+	start             time.Time               `yaml:"-"`
+	end               time.Time               `yaml:"-"`
+	success           bool                    `yaml:"-"`
+	results           []shared.TaskResult     `yaml:"-"`
+	notified          map[string]bool         `yaml:"-"`
+	macroStack        []string                `yaml:"-"`
+	ancestors         []string                `yaml:"-"` // Files of the parent workflows of a child workflow
+	pending           []*asyncTask            `yaml:"-"` // Background tasks that have not been awaited
+	stepMode          bool                    `yaml:"-"` // Ask the operator before running each task
+	planMode          bool                    `yaml:"-"` // Plan changes instead of making them
+	readOnly          bool                    `yaml:"-"` // Refuse tasks that change something, regardless of read_only
+	policy            *policy.Policy          `yaml:"-"` // Rules every task must satisfy before it is executed
+	confirmed         []string                `yaml:"-"` // AWS accounts confirmed in advance for aws_guard
+	trustedKeys       []ed25519.PublicKey     `yaml:"-"` // Keys that must sign workflow files, if any
+	signedFiles       map[string]string       `yaml:"-"` // Hashes of files covered by verified signatures
+	auditFile         string                  `yaml:"-"` // Audit log set by the caller, overrides audit_log
+	audit             *audit.Log              `yaml:"-"` // Audit log of the tasks that change something
+	heldLocks         map[string]*atomic.Bool `yaml:"-"` // Locks held by the workflow and its parents, set if lost
+	clock             window.Clock            `yaml:"-"` // Clock used to check windows
+	gates             []*window.Gate          `yaml:"-"` // Windows of the workflow and its parents
+	parentGates       []*window.Gate          `yaml:"-"` // Windows of the parent workflows of a child workflow
+	environment       string                  `yaml:"-"` // Name of the selected environment
+	selected          *Environment            `yaml:"-"` // Settings of the selected environment
+	parentEnvironment *Environment            `yaml:"-"` // Settings of the environment of the parent workflow
+	digest            string                  `yaml:"-"` // Hash of the loaded workflow file
+}
+
+// position identifies where a list of tasks runs: its phase and, for nested lists such as the
+// tasks of a macro, the position of the parent task within that phase
+type position struct {
+	phase  string
+	parent string
+}
+
+// taskList is a named list of tasks in the workflow
+type taskList struct {
+	name  string
+	tasks []map[string]any
 }
 
 // Option is used for the golang options pattern
@@ -47,9 +125,11 @@ func New(options ...Option) *Workflow {
 		DryRun:   false,
 		Debug:    false,
 		JSON:     false,
+		Output:   "",
 		Env:      "",
 		callback: nil,
 		Tasks:    make([]map[string]any, 0),
+		clock:    window.SystemClock,
 	}
 	for _, opt := range options {
 		opt(w)
@@ -76,6 +156,16 @@ func WithJSON(b bool) Option {
 	}
 }
 
+// WithOutput sets the output mode (text, json, or ndjson) on the Workflow
+// Note that if "output" is present in the workflow, it will override this setting
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithOutput(mode string) Option {
+	return func(w *Workflow) {
+		w.Output = mode
+	}
+}
+
 // WithDebug sets Debug on the Workflow
 // Note that if "debug" is present in the workflow, it will override this setting
 //
@@ -96,6 +186,96 @@ func WithDryRun(b bool) Option {
 	}
 }
 
+// WithStep sets step mode on the Workflow. In step mode the operator is asked before each task is run
+// whether to run it, skip it, or abort the workflow.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithStep(b bool) Option {
+	return func(w *Workflow) {
+		w.stepMode = b
+	}
+}
+
+// WithPlan sets plan mode on the Workflow. In plan mode tasks that implement shared.Planner describe the
+// changes they would make instead of making them, while other tasks are executed normally so that their
+// data is available to later tasks.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithPlan(b bool) Option {
+	return func(w *Workflow) {
+		w.planMode = b
+	}
+}
+
+// WithReadOnly sets read-only mode on the Workflow. A read-only workflow that contains a task registered
+// as mutating fails before any task is run. Unlike WithDryRun, it cannot be turned off by the workflow file.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithReadOnly(b bool) Option {
+	return func(w *Workflow) {
+		w.readOnly = b
+	}
+}
+
+// WithPolicy sets the policy that every task must satisfy before it is executed. A task that violates a
+// rule fails, and the violation is reported as a policy_violation event.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithPolicy(p *policy.Policy) Option {
+	return func(w *Workflow) {
+		w.policy = p
+	}
+}
+
+// WithConfirmedAccounts confirms AWS accounts in advance, so that aws_guard does not ask the operator to
+// confirm them. This allows unattended runs against accounts that require confirmation.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithConfirmedAccounts(accounts []string) Option {
+	return func(w *Workflow) {
+		w.confirmed = accounts
+	}
+}
+
+// WithTrustedKeys requires workflow files to be signed by one of the keys. Load refuses a workflow file,
+// or a child workflow file run by workflow_run, that is not signed or was modified after it was signed.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithTrustedKeys(keys []ed25519.PublicKey) Option {
+	return func(w *Workflow) {
+		w.trustedKeys = keys
+	}
+}
+
+// WithAuditLog records every task that changes something in an audit log file. It overrides the audit_log
+// setting of the workflow file.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithAuditLog(filename string) Option {
+	return func(w *Workflow) {
+		w.auditFile = filename
+	}
+}
+
+// WithClock sets the clock used to check maintenance windows and change freezes. It is used in tests.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithClock(c window.Clock) Option {
+	return func(w *Workflow) {
+		w.clock = c
+	}
+}
+
+// WithEnvironment selects one of the environments defined in the workflow file. Its settings replace or add
+// to the settings of the file, and its name is available to tasks as the environment variable.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithEnvironment(name string) Option {
+	return func(w *Workflow) {
+		w.environment = name
+	}
+}
+
 // Load reads a task configuration from a file or stdin
 //
 //goland:noinspection GoUnusedExportedFunction
@@ -105,6 +285,17 @@ func (w *Workflow) Load(filename string) error {
 
 	// Dump all existing workflow
 	w.Tasks = make([]map[string]any, 0)
+	w.OnFailure = nil
+	w.OnSuccess = nil
+	w.Handlers = nil
+	w.Macros = nil
+	w.AWSGuard = nil
+	w.Secrets = shared.SecretsConfig{}
+	w.AuditLog = ""
+	w.Lock = nil
+	w.Window = nil
+	w.Environments = nil
+	w.source = filename
 
 	// Read the file or stdin
 	if filename == "" {
@@ -119,6 +310,14 @@ func (w *Workflow) Load(filename string) error {
 		}
 	}
 
+	// Verify the data that is used, so that the file cannot be replaced after it is verified
+	if err = w.verifySignature(filename, data); err != nil {
+		return err
+	}
+
+	// Dry runs required by an environment are recorded for this version of the workflow
+	w.digest = signature.Hash(data)
+
 	// Unmarshal the data
 	if err = yaml.Unmarshal(data, &w); err != nil {
 		return fmt.Errorf("deserialization error: %w", err)
@@ -157,138 +356,592 @@ func (w *Workflow) AddTaskYAML(task []byte) error {
 	return nil
 }
 
+// OutputMode returns the effective output mode. The legacy "json" setting is treated as OutputJSON.
+func (w *Workflow) OutputMode() string {
+	switch w.Output {
+	case OutputJSON, OutputNDJSON:
+		return w.Output
+	}
+	if w.JSON {
+		return OutputJSON
+	}
+	return OutputText
+}
+
+// RunID returns the unique ID of the current or most recent run
+func (w *Workflow) RunID() string {
+	return w.runID
+}
+
 // Execute the loaded workflow
 //
 //goland:noinspection GoUnusedExportedFunction
 func (w *Workflow) Execute() bool {
-	var err error
-	var count int
-
-	// Create a task context, defaulting to global file settings
-	var taskContext = shared.TaskContext{
-		Env:          w.Env,
-		DryRun:       w.DryRun,
-		Debug:        w.Debug,
-		Instructions: make([]byte, 0),
+	w.runID = newRunID()
+	w.start = time.Now()
+	w.results = make([]shared.TaskResult, 0, len(w.Tasks))
+	w.notified = make(map[string]bool)
+	w.macroStack = nil
+	w.pending = nil
+	if w.AWSGuard != nil {
+		w.AWSGuard.Preconfirm(w.confirmed)
 	}
 
-	// Iterate over the tasks
-	for _, rawTask := range w.Tasks {
-		count++
-		taskName, ok := rawTask["name"].(string)
-		if !ok {
-			taskName = ""
+	// A workflow's secrets settings apply until it ends, a child workflow without settings uses its parent's
+	if w.Secrets != (shared.SecretsConfig{}) {
+		secrets := w.Secrets
+		if secrets.File != "" && !filepath.IsAbs(secrets.File) && w.source != "" {
+			secrets.File = filepath.Join(filepath.Dir(w.source), secrets.File)
 		}
+		defer shared.ConfigureSecrets(shared.ConfigureSecrets(secrets))
+	}
 
-		taskType, ok := rawTask["task"].(string)
-		if !ok {
-			taskType = ""
+	// Structured output must be the only thing on stdout, so send human chatter to stderr
+	if w.OutputMode() == OutputNDJSON && shared.Console == nil {
+		shared.Console = os.Stderr
+	}
+
+	w.event(shared.WorkflowEvent{MessageType: "workflow_start", Data: map[string]any{"tasks": len(w.Tasks)}})
+	w.success = w.execute()
+	if w.success {
+		if err := w.recordDryRun(); err != nil {
+			w.workflowError(err)
+			w.success = false
 		}
+	}
+	w.end = time.Now()
+	w.recap()
+	if w.planMode {
+		w.plan()
+	}
+	w.event(shared.WorkflowEvent{MessageType: "workflow_end", Data: map[string]any{"success": w.success}})
+	return w.success
+}
 
-		skip, ok := rawTask["skip"].(bool)
-		if !ok {
-			skip = false
+// Results returns the results of all tasks in the current or most recent run, in completion order
+func (w *Workflow) Results() []shared.TaskResult {
+	return w.results
+}
+
+// Summary returns a summary of the most recent run, including all task results with timing
+func (w *Workflow) Summary() shared.RunSummary {
+	name := w.Name
+	if name == "" {
+		if w.source != "" {
+			name = filepath.Base(w.source)
+		} else {
+			name = "stdin"
 		}
+	}
+	return shared.RunSummary{
+		RunID:     w.runID,
+		Name:      name,
+		StartTime: w.start,
+		EndTime:   w.end,
+		Success:   w.success,
+		Results:   w.results,
+	}
+}
 
-		errorMessage, ok := rawTask["error_message"].(string)
-		if !ok {
-			errorMessage = ""
+// Validate checks the workflow for errors that can be detected before any task is run
+func (w *Workflow) Validate() error {
+	if err := w.validateHandlers(); err != nil {
+		return err
+	}
+	if err := w.validateMacros(); err != nil {
+		return err
+	}
+	if err := w.validateLoops(); err != nil {
+		return err
+	}
+	if err := w.validateReadOnly(); err != nil {
+		return err
+	}
+	if err := w.validateLock(); err != nil {
+		return err
+	}
+	if err := w.validateWindows(); err != nil {
+		return err
+	}
+	return w.validateAsync()
+}
+
+// lists returns all task lists in the workflow, including the task lists of macros and the
+// between_batches lists of looped tasks
+func (w *Workflow) lists() []taskList {
+	lists := []taskList{
+		{name: "tasks", tasks: w.Tasks},
+		{name: PhaseOnFailure, tasks: w.OnFailure},
+		{name: PhaseOnSuccess, tasks: w.OnSuccess},
+		{name: PhaseHandlers, tasks: w.Handlers},
+	}
+	lists = append(lists, w.macroLists()...)
+	return append(lists, betweenLists(lists)...)
+}
+
+// execute runs the main task list and notified handlers, followed by the on_success or on_failure list
+func (w *Workflow) execute() bool {
+	if err := w.selectEnvironment(); err != nil {
+		w.workflowError(err)
+		return false
+	}
+	if err := w.Validate(); err != nil {
+		w.workflowError(err)
+		return false
+	}
+	if err := w.checkDryRun(); err != nil {
+		w.workflowError(err)
+		return false
+	}
+	if err := w.openAudit(); err != nil {
+		w.workflowError(err)
+		return false
+	}
+
+	// The lock is held until the last on_failure or on_success task has run
+	release, err := w.acquireLock()
+	if err != nil {
+		w.workflowError(err)
+		return false
+	}
+	defer release()
+
+	// Background tasks that are still running when the workflow ends, such as those of a failed on_success
+	// list, are cancelled and have finished before the lock is released
+	defer w.cancelPending()
+
+	// Background tasks that were not awaited are awaited before the handlers run
+	if w.runList(position{}, w.Tasks, false) && w.awaitPending() && w.runHandlers() {
+		// A failure of an on_success task (or a handler it notifies) fails the workflow
+		return w.runList(position{phase: PhaseOnSuccess}, w.OnSuccess, false) && w.awaitPending() && w.runHandlers()
+	}
+
+	// The failed task is found before the background tasks of the failed task list are cancelled, as
+	// their cancelled results are recorded after it
+	failed, found := w.lastFailure()
+
+	// Background tasks of the failed task list are no longer needed
+	w.cancelPending()
+
+	// Expose the failed task to the on_failure tasks
+	if len(w.OnFailure) > 0 && found {
+		shared.SetVar("failed_task_name", failed.Name)
+		shared.SetVar("failed_task_task", failed.Task)
+		shared.SetVar("failed_task_sequence", failed.Sequence)
+		shared.SetVar("failed_task_msg", failed.Msg)
+		shared.SetVar("failed_task_error_message", failed.ErrorMessage)
+	}
+
+	// The on_failure tasks are best-effort and can never make the workflow succeed
+	w.runList(position{phase: PhaseOnFailure}, w.OnFailure, true)
+	w.awaitPending()
+	return false
+}
+
+// lastFailure returns the most recent result of a task that failed and was neither ignored nor cancelled
+func (w *Workflow) lastFailure() (shared.TaskResult, bool) {
+	for i := len(w.results) - 1; i >= 0; i-- {
+		if r := w.results[i]; !r.Success && !r.Ignored && !r.Cancelled {
+			return r, true
 		}
+	}
+	return shared.TaskResult{}, false
+}
 
-		taskContext.Name = taskName
-		taskContext.Task = taskType
-		taskContext.Sequence = count
-		taskContext.ErrorMessage = errorMessage
+// runHandlers runs any handlers that were notified but not yet run
+func (w *Workflow) runHandlers() bool {
+	_, ok := w.flushHandlers()
+	return ok
+}
 
-		if taskType == "" {
-			if w.taskEnd(taskContext.Error(fmt.Sprintf("%s: Task type is missing or not a string\n", taskContext.String()), nil)) {
+// runList executes a list of tasks in order. Unless bestEffort is true, it stops at the first
+// task that fails. It returns true if all tasks succeeded (or their failures were ignored).
+func (w *Workflow) runList(pos position, tasks []map[string]any, bestEffort bool) bool {
+	success := true
+	for i, rawTask := range tasks {
+		// Start background tasks, or run the task and process the result, and stop if necessary
+		var ok bool
+		skip, _ := rawTask["skip"].(bool)
+		switch {
+		case isLoop(rawTask) && !skip:
+			ok = w.runLoop(pos, i+1, rawTask)
+		case isAsync(rawTask):
+			ok = w.startAsync(pos, i+1, rawTask)
+		default:
+			ok = w.taskEnd(rawTask, w.runTask(pos, i+1, rawTask))
+		}
+		if !ok {
+			success = false
+			if !bestEffort {
 				return false
 			}
-			continue
 		}
+	}
+	return success
+}
+
+// runTask executes a single raw task and returns its result. The caller is responsible for
+// passing the result to taskEnd.
+func (w *Workflow) runTask(pos position, sequence int, rawTask map[string]any) shared.TaskResult {
+	start := time.Now()
+	return placeResult(w.executeTask(pos, sequence, rawTask), pos, start)
+}
+
+// placeResult sets the position and start time of a task result
+func placeResult(result shared.TaskResult, pos position, startTime time.Time) shared.TaskResult {
+	result.Phase = pos.phase
+	result.Parent = pos.parent
+	result.StartTime = startTime
+	return result
+}
+
+// executeTask prepares and executes a task
+func (w *Workflow) executeTask(pos position, sequence int, rawTask map[string]any) shared.TaskResult {
+	run, result := w.prepareTask(pos, sequence, rawTask, nil)
+	if run == nil {
+		return result
+	}
+	return finishTask(run())
+}
+
+// prepareTask builds the task context, looks up the task in the registry (or the engine built-ins), and
+// sends the task start information. It returns a function that executes the task, or nil and a result if
+// the task is not to be executed because it is invalid or skipped. The cancel channel is passed to the
+// task in its context, and may be nil.
+func (w *Workflow) prepareTask(pos position, sequence int, rawTask map[string]any, cancel <-chan struct{}) (func() shared.TaskResult, shared.TaskResult) {
+	var err error
+
+	taskName, ok := rawTask["name"].(string)
+	if !ok {
+		taskName = ""
+	}
+
+	taskType, ok := rawTask["task"].(string)
+	if !ok {
+		taskType = ""
+	}
+
+	skip, ok := rawTask["skip"].(bool)
+	if !ok {
+		skip = false
+	}
+
+	errorMessage, ok := rawTask["error_message"].(string)
+	if !ok {
+		errorMessage = ""
+	}
+
+	// Create a task context, defaulting to global file settings
+	var taskContext = shared.TaskContext{
+		Env:          w.envFile(),
+		DryRun:       w.DryRun,
+		Debug:        w.Debug,
+		Name:         taskName,
+		Task:         taskType,
+		Sequence:     sequence,
+		ErrorMessage: errorMessage,
+		Instructions: make([]byte, 0),
+		Cancel:       cancel,
+		AWSGuard:     w.AWSGuard,
+		Audit:        w.audit != nil && shared.IsMutating(taskType),
+	}
+	if w.selected != nil {
+		taskContext.AWSProfile = w.selected.AWSProfile
+		taskContext.AWSRegion = w.selected.AWSRegion
+	}
 
-		if skip {
-			r := taskContext.Result(true, "Task skipped", nil)
+	// The task's own env file replaces the default, for its secrets as for its clients
+	if taskEnv, ok := rawTask["env"].(string); ok {
+		taskContext.Env = shared.SelectEnv(shared.ResolveVars(taskEnv).(string), taskContext.Env)
+	}
+
+	if taskType == "" {
+		return nil, taskContext.Error(fmt.Sprintf("%s: Task type is missing or not a string\n", taskContext.String()), nil)
+	}
+
+	if skip {
+		r := taskContext.Result(true, "Task skipped", nil)
+		r.MessageType = "task_skipped"
+		r.Skipped = true
+		return nil, r
+	}
+
+	// In step mode the operator decides whether the task is run
+	if w.stepMode {
+		switch w.stepPrompt(pos, sequence, rawTask) {
+		case stepSkip:
+			r := taskContext.Result(true, "Task skipped by operator", nil)
 			r.MessageType = "task_skipped"
-			if !w.taskEnd(r) {
-				break
-			}
-			continue
+			r.Skipped = true
+			return nil, r
+		case stepAbort:
+			return nil, taskContext.Error("Workflow aborted by operator", nil)
 		}
+	}
 
-		// Obtain the task constructor from the registry
-		constructor, ok := shared.TaskRegistry[taskType]
-		if !ok {
-			if !w.taskEnd(taskContext.Error(fmt.Sprintf("Invalid task: %s", taskType), nil)) {
-				return false
-			}
-			continue
+	// Secret references are resolved before the task runs, so that a task fails rather than receiving an
+	// empty string if a secret cannot be resolved
+	if err = shared.ResolveSecrets(rawTask, taskContext.Env); err != nil {
+		return nil, taskContext.Error("Unable to resolve secrets", err)
+	}
+
+	// Engine built-in tasks operate on the workflow itself, so they are not in the registry
+	builtin := w.builtin(taskType)
+
+	// Obtain the task constructor from the registry
+	constructor, ok := shared.TaskRegistry[taskType]
+	if !ok && builtin == nil {
+		return nil, taskContext.Error(fmt.Sprintf("Invalid task: %s", taskType), nil)
+	}
+
+	// Validate refuses a read-only workflow with mutating tasks before it runs, this is a safeguard
+	if w.isReadOnly() && shared.IsMutating(taskType) {
+		return nil, taskContext.Error(fmt.Sprintf("%s changes something and cannot be run in read-only mode", taskType), nil)
+	}
+
+	// Another run may hold a lost lock, so nothing is changed once it is lost
+	if shared.IsMutating(taskType) {
+		if name := w.lostLock(); name != "" {
+			return nil, taskContext.Error(fmt.Sprintf("lock %s was lost, %s changes something and cannot be run", name, taskType), nil)
 		}
+	}
 
-		// Tasks can have different structures, so they are initial deserialized into a map[string]any
-		// to obtain information such as the task name and type. To make it easier for individual tasks,
-		// the raw task is then serialized into a byte slice and passed to the task as a single field.
-		// This allows the task to deserialize the raw task into its own struct rather than have to deal
-		// with the raw map[string]any.
-		taskContext.Instructions, err = json.Marshal(rawTask)
-		if err != nil {
-			if !w.taskEnd(taskContext.Error("Failed to serialize task", err)) {
-				return false
-			}
-			continue
+	// Tasks can have different structures, so they are initial deserialized into a map[string]any
+	// to obtain information such as the task name and type. To make it easier for individual tasks,
+	// the raw task is then serialized into a byte slice and passed to the task as a single field.
+	// This allows the task to deserialize the raw task into its own struct rather than have to deal
+	// with the raw map[string]any.
+	taskContext.Instructions, err = json.Marshal(rawTask)
+	if err != nil {
+		return nil, taskContext.Error("Failed to serialize task", err)
+	}
+
+	// Tasks that violate the policy are not executed, in any mode
+	if builtin == nil {
+		if r, ok := w.checkPolicy(pos, rawTask, constructor, taskContext); !ok {
+			return nil, r
+		}
+	}
+
+	// Tasks are only run in their maintenance windows
+	if builtin == nil {
+		if r, ok := w.checkWindow(pos, rawTask, taskContext); !ok {
+			return nil, r
 		}
+	}
 
-		// Send the task start information
-		w.taskStart(shared.TaskInfo{
-			MessageType:  "task_start",
-			Sequence:     taskContext.Sequence,
-			Name:         taskContext.Name,
-			Task:         taskContext.Task,
-			Instructions: rawTask,
-			Debug:        taskContext.Debug,
-		})
+	// Send the task start information
+	w.taskStart(shared.TaskInfo{
+		MessageType:  "task_start",
+		Phase:        pos.phase,
+		Parent:       pos.parent,
+		Sequence:     taskContext.Sequence,
+		Name:         taskContext.Name,
+		Task:         taskContext.Task,
+		Environment:  w.environment,
+		Instructions: rawTask,
+		Debug:        taskContext.Debug,
+	})
+
+	if builtin != nil {
+		return func() shared.TaskResult {
+			return builtin(pos, rawTask, taskContext)
+		}, shared.TaskResult{}
+	}
 
+	run := func() shared.TaskResult {
 		// Call the task's constructor, which returns an object that implements the
 		// shared.Task interface
 		task := constructor(taskContext)
 
+		// In plan mode, tasks that change something only describe the change. Mutating tasks that
+		// cannot describe the change are run as a dry run instead.
+		if w.planMode {
+			if planner, ok := task.(shared.Planner); ok {
+				return planner.Plan()
+			}
+			if shared.IsMutating(taskType) {
+				taskContext.DryRun = true
+				task = constructor(taskContext)
+			}
+		}
+
 		// Execute the task
-		result := task.Execute()
+		return task.Execute()
+	}
+	if !taskContext.Audit {
+		return run, shared.TaskResult{}
+	}
 
-		// Force the message type
-		result.MessageType = "task_stop"
+	// Audited tasks record their parameters as they are when the task starts, without secret values
+	parameters, _ := shared.RedactAny(shared.ResolveVars(rawTask)).(map[string]any)
+	return func() shared.TaskResult {
+		start := time.Now()
+		result := run()
+		return w.auditTask(pos, taskContext, parameters, start, result)
+	}, shared.TaskResult{}
+}
 
-		// Copy returned data to variables
-		if !result.NoVars {
-			for key, value := range result.Data {
-				shared.SetVar(key, value)
-			}
-		}
+// finishTask completes the result of an executed task and copies its data to variables
+func finishTask(result shared.TaskResult) shared.TaskResult {
+	// Force the message type
+	result.MessageType = "task_stop"
 
-		// Process the result and break if necessary
-		if !w.taskEnd(result) {
-			return false
+	// Copy returned data to variables
+	if !result.NoVars {
+		for key, value := range result.Data {
+			shared.SetVar(key, value)
 		}
 	}
-	return true
+
+	return result
 }
 
 // Dump pretty-prints the loaded workflow
 func (w *Workflow) Dump() {
 	fmt.Printf("Global dryrun: %t\n", w.DryRun)
 	fmt.Printf("Global debug: %t\n", w.Debug)
-	for i, task := range w.Tasks {
+	dumpList("Task", w.Tasks)
+	dumpList("on_failure task", w.OnFailure)
+	dumpList("on_success task", w.OnSuccess)
+	dumpList("Handler", w.Handlers)
+	for _, list := range w.macroLists() {
+		dumpList(list.name+" task", list.tasks)
+	}
+}
+
+// dumpList pretty-prints a list of tasks
+func dumpList(label string, tasks []map[string]any) {
+	for i, task := range tasks {
 		data, err := json.MarshalIndent(task, "", "  ")
 		if err != nil {
-			fmt.Printf("Failed to marshal task %d: %v\n", i+1, err)
+			fmt.Printf("Failed to marshal %s %d: %v\n", label, i+1, err)
 			continue
 		}
-		fmt.Printf("Task %d:\n%s\n\n", i+1, string(data))
+		fmt.Printf("%s %d:\n%s\n\n", label, i+1, shared.Redact(string(data)))
+	}
+}
+
+// event either passes a workflow event to the callback function (if it implements shared.EventCallback)
+// or prints it to stdout. Workflow events are only printed in NDJSON mode to keep the other modes unchanged.
+func (w *Workflow) event(event shared.WorkflowEvent) {
+	event.Msg = shared.Redact(event.Msg)
+	if data, ok := shared.RedactAny(event.Data).(map[string]any); ok {
+		event.Data = data
+	}
+	event.RunID = w.runID
+	event.Timestamp = time.Now()
+
+	if w.callback != nil {
+		if ec, ok := w.callback.(shared.EventCallback); ok {
+			ec.OnEvent(event)
+		}
+		return
+	}
+
+	if w.OutputMode() == OutputNDJSON {
+		fmt.Println(event.Serialize())
+	}
+}
+
+// progress reports a workflow event that marks progress within a task, such as the start and end of a
+// batch. It is passed to the callback (if it implements shared.EventCallback) or printed in the current
+// output mode.
+func (w *Workflow) progress(event shared.WorkflowEvent) {
+	if w.callback != nil || w.OutputMode() == OutputNDJSON {
+		w.event(event)
+		return
+	}
+
+	event.RunID = w.runID
+	event.Timestamp = time.Now()
+	if w.OutputMode() == OutputJSON {
+		fmt.Println(event.SerializePretty())
+	} else {
+		fmt.Println(event.String())
+	}
+	fmt.Println()
+}
+
+// workflowError reports an error that prevents the workflow from running. It is passed to the
+// callback (if it implements shared.EventCallback) as a workflow_error event, or printed.
+func (w *Workflow) workflowError(err error) {
+	if w.callback == nil && w.OutputMode() != OutputNDJSON {
+		shared.Printf("Error: %s\n\n", err.Error())
+	}
+	w.event(shared.WorkflowEvent{MessageType: "workflow_error", Msg: err.Error()})
+}
+
+// recap reports the outcome of the run. It is passed to the callback (if it implements shared.EventCallback)
+// as a workflow_recap event, or printed to stdout in the current output mode.
+func (w *Workflow) recap() {
+	summary := w.Summary()
+	recap := summary.Recap()
+	event := shared.WorkflowEvent{
+		MessageType: "workflow_recap",
+		RunID:       w.runID,
+		Timestamp:   time.Now(),
+		Msg: fmt.Sprintf("ok=%d changed=%d failed=%d ignored=%d cancelled=%d skipped=%d",
+			recap.Ok, recap.Changed, recap.Failed, recap.Ignored, recap.Cancelled, recap.Skipped),
+		Data: map[string]any{"recap": recap},
+	}
+
+	if w.callback != nil {
+		if ec, ok := w.callback.(shared.EventCallback); ok {
+			ec.OnEvent(event)
+		}
+		return
+	}
+
+	switch w.OutputMode() {
+	case OutputNDJSON:
+		fmt.Println(event.Serialize())
+	case OutputJSON:
+		fmt.Println(event.SerializePretty())
+		fmt.Println()
+	default:
+		fmt.Println(recap.String())
+		fmt.Println()
+	}
+}
+
+// plan reports the changes planned by the run. It is passed to the callback (if it implements
+// shared.EventCallback) as a workflow_plan event, or printed to stdout in the current output mode.
+func (w *Workflow) plan() {
+	summary := w.Summary()
+	plans := summary.Plan()
+	event := shared.WorkflowEvent{
+		MessageType: "workflow_plan",
+		RunID:       w.runID,
+		Timestamp:   time.Now(),
+		Msg:         fmt.Sprintf("%d task(s) planned changes", len(plans)),
+		Data:        map[string]any{"plan": plans},
+	}
+
+	if w.callback != nil {
+		if ec, ok := w.callback.(shared.EventCallback); ok {
+			ec.OnEvent(event)
+		}
+		return
+	}
+
+	switch w.OutputMode() {
+	case OutputNDJSON:
+		fmt.Println(event.Serialize())
+	case OutputJSON:
+		fmt.Println(event.SerializePretty())
+		fmt.Println()
+	default:
+		fmt.Println(shared.PlanString(plans))
+		fmt.Println()
 	}
 }
 
 // taskStart either passes the task information to the startCallback function or prints them to stdout
 func (w *Workflow) taskStart(task shared.TaskInfo) bool {
+	task = task.Redacted()
+	task.RunID = w.runID
+	task.Timestamp = time.Now()
 
 	// If a callback function is set, pass it the task information
 	if w.callback != nil {
@@ -296,9 +949,13 @@ func (w *Workflow) taskStart(task shared.TaskInfo) bool {
 	}
 
 	// Output to the console
-	if w.JSON {
+	switch w.OutputMode() {
+	case OutputNDJSON:
+		fmt.Println(task.Serialize())
+		return true
+	case OutputJSON:
 		fmt.Println(task.SerializePretty())
-	} else {
+	default:
 		fmt.Println(task.String())
 	}
 	fmt.Println()
@@ -307,22 +964,55 @@ func (w *Workflow) taskStart(task shared.TaskInfo) bool {
 	return true
 }
 
-// taskEnd either passes the results to the callback function or prints them to stdout
-func (w *Workflow) taskEnd(result shared.TaskResult) bool {
+// taskEnd evaluates the task's changed_when, failed_when, and ignore_errors settings, notifies any handlers,
+// and reports the result. It returns true if the workflow should continue.
+func (w *Workflow) taskEnd(rawTask map[string]any, result shared.TaskResult) bool {
+	result = evaluateResult(rawTask, result)
 
-	// If a callback function is set, pass it the results
-	if w.callback != nil {
-		return w.callback.OnStop(result)
+	// Notify handlers if the task changed something
+	w.notify(rawTask, result)
+
+	return w.taskStop(result)
+}
+
+// taskStop records the result and either passes it to the callback function or prints it to stdout.
+// It returns true if the workflow should continue: the task succeeded or its failure is ignored, and the
+// callback, if any, did not stop the workflow.
+func (w *Workflow) taskStop(result shared.TaskResult) bool {
+	result = result.Redacted()
+	result.RunID = w.runID
+	result.Timestamp = time.Now()
+	if result.StartTime.IsZero() {
+		result.StartTime = result.Timestamp
 	}
 
-	// Output to the console
-	if w.JSON {
+	// Retain the result for reporting
+	w.results = append(w.results, result)
+
+	cont := true
+	switch {
+	case w.callback != nil:
+		// If a callback function is set, pass it the results
+		cont = w.callback.OnStop(result)
+	case w.OutputMode() == OutputNDJSON:
+		fmt.Println(result.Serialize())
+	case w.OutputMode() == OutputJSON:
 		fmt.Println(result.SerializePretty())
-	} else {
+		fmt.Println()
+	default:
 		fmt.Println(result.String())
+		fmt.Println()
 	}
-	fmt.Println()
 
-	// Only continue if there was success
-	return result.Success
+	// Only continue if there was success or the failure is ignored
+	return cont && (result.Success || result.Ignored)
+}
+
+// newRunID returns a random identifier for a workflow run
+func newRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
 }